@@ -0,0 +1,430 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+)
+
+const ConnectedClientsPollInterval = 2 * time.Second
+
+type History struct {
+	list   []*ChatEntry
+	unread int
+}
+
+type Chat struct {
+	// User
+	Name string
+	Id   uuid.UUID
+
+	chatHistoryMu sync.Mutex
+	chatHistory   map[uuid.UUID]*History
+
+	ConnectedUsers []uuid.UUID
+
+	currentRecipientMu sync.Mutex
+	currentRecipientId *uuid.UUID
+
+	conn   *grpc.ClientConn
+	client pb.ChatServerClient
+
+	recvCh chan proto.Message
+	sendCh chan string
+	// closed once the message stream ends, recvCh is never closed as
+	// several goroutines send on it
+	done chan struct{}
+
+	connectedClientsMu sync.Mutex
+	connectedClients   map[uuid.UUID]*ConnectedClient
+}
+
+func NewChat(username string) *Chat {
+	c := Chat{Name: username, chatHistory: make(map[uuid.UUID]*History)}
+	c.recvCh = make(chan proto.Message, 10)
+	c.sendCh = make(chan string, 10)
+	c.done = make(chan struct{})
+	c.connectedClients = make(map[uuid.UUID]*ConnectedClient)
+	return &c
+}
+
+func (c *Chat) GetHistoryFor(id uuid.UUID) []*ChatEntry {
+	c.chatHistoryMu.Lock()
+	defer c.chatHistoryMu.Unlock()
+	connectedClient, err := c.FindById(id)
+	if err != nil {
+		log.Printf("error getting history: %s", err)
+		return nil
+	}
+	log.Printf("getting history for %s, clearing unread\n", connectedClient.name)
+
+	h, ok := c.chatHistory[id]
+	if !ok {
+		log.Printf("no history for %s\n", connectedClient.name)
+		return []*ChatEntry{}
+	}
+	h.unread = 0
+	return h.list
+}
+
+func (c *Chat) AppendHistory(senderId, recipientId uuid.UUID, message string) ChatEntry {
+	sender, err := c.FindById(senderId)
+	if err != nil {
+		log.Printf("could not find sender: %s", err)
+		return ChatEntry{}
+	}
+	recipient, err := c.FindById(recipientId)
+	if err != nil {
+		log.Printf("could not find recipient: %s", err)
+		return ChatEntry{}
+	}
+	e := ChatEntry{Sender: sender.name, Recipient: recipient.name, Time: time.Now(), Text: message, senderId: sender.id, recipientId: recipient.id}
+
+	c.chatHistoryMu.Lock()
+	defer c.chatHistoryMu.Unlock()
+
+	var target uuid.UUID
+	if sender.id == c.Id {
+		// messages sent by me are saved in chat history with recepient
+		target = recipient.id
+	} else {
+		// messages sent to me are saved in chat history of the sender
+		target = sender.id
+	}
+	log.Printf("saving message sent by %s to %s to %s[%s] = %s\n", sender.name, recipient.name, c.Name, target, message)
+	h := c.chatHistory[target]
+	if h == nil {
+		h = &History{}
+		c.chatHistory[target] = h
+	}
+
+	h.list = append(h.list, &e)
+	return e
+}
+
+func (c *Chat) Send(message string) {
+	select {
+	case c.sendCh <- message:
+	case <-c.done:
+		log.Printf("message stream closed, dropping message: %s", message)
+	}
+}
+
+func (c *Chat) Find(displayName string) (*ConnectedClient, error) {
+	c.connectedClientsMu.Lock()
+	defer c.connectedClientsMu.Unlock()
+	for _, v := range c.connectedClients {
+		if v.DisplayName() == displayName {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not connected", displayName)
+}
+
+func (c *Chat) FindById(id uuid.UUID) (*ConnectedClient, error) {
+	c.connectedClientsMu.Lock()
+	defer c.connectedClientsMu.Unlock()
+	v, ok := c.connectedClients[id]
+	if !ok {
+		return nil, fmt.Errorf("%s not connected", id)
+	}
+	return v, nil
+}
+
+// Recv returns the next message received from the server, either
+// a *pb.ChatMessage or a *pb.ConnectedClientsResponse. It returns false
+// once the server closed the message stream.
+func (c *Chat) Recv() (proto.Message, bool) {
+	select {
+	case msg := <-c.recvCh:
+		return msg, true
+	case <-c.done:
+		return nil, false
+	}
+}
+
+// deliver queues msg for Recv, it returns false once the message stream
+// has ended and nobody is receiving anymore.
+func (c *Chat) deliver(msg proto.Message) bool {
+	select {
+	case c.recvCh <- msg:
+		return true
+	case <-c.done:
+		return false
+	}
+}
+
+func (c *Chat) GetConnectedClients() map[uuid.UUID]*ConnectedClient {
+	c.connectedClientsMu.Lock()
+	defer c.connectedClientsMu.Unlock()
+	return c.connectedClients
+}
+
+// SetConnectedClients replaces the connected clients and returns the old
+// names of those that were renamed since the last call.
+func (c *Chat) SetConnectedClients(connectedClients []*pb.ConnectedClientsResponse_ConnectedClient) map[uuid.UUID]string {
+	c.connectedClientsMu.Lock()
+	defer c.connectedClientsMu.Unlock()
+	renamed := make(map[uuid.UUID]string)
+	clientsSet := make(map[uuid.UUID]struct{})
+	for _, cc := range connectedClients {
+		id, err := uuid.Parse(cc.GetId())
+		if err != nil {
+			log.Printf("skipping client with invalid id %q: %v", cc.GetId(), err)
+			continue
+		}
+		clientsSet[id] = struct{}{}
+		known, ok := c.connectedClients[id]
+		if !ok {
+			c.connectedClients[id] = &ConnectedClient{id: id, name: cc.GetName(), status: cc.GetProfile().GetStatus()}
+			continue
+		}
+		if known.name != cc.GetName() {
+			renamed[id] = known.name
+			known.name = cc.GetName()
+		}
+		known.status = cc.GetProfile().GetStatus()
+	}
+	// need to remove elements not present in connectedClients
+	for k := range c.connectedClients {
+		if _, ok := clientsSet[k]; !ok {
+			delete(c.connectedClients, k)
+		}
+	}
+	return renamed
+}
+
+// Rename relabels the history of the client with id, which is now known
+// as name.
+func (c *Chat) Rename(id uuid.UUID, name string) {
+	if id == c.Id {
+		c.Name = name
+	}
+	c.chatHistoryMu.Lock()
+	defer c.chatHistoryMu.Unlock()
+	for _, h := range c.chatHistory {
+		for _, e := range h.list {
+			if e.senderId == id {
+				e.Sender = name
+			}
+			if e.recipientId == id {
+				e.Recipient = name
+			}
+		}
+	}
+}
+
+func (c *Chat) Start() error {
+	err := c.connectToChatServer()
+	if err != nil {
+		return fmt.Errorf("error connecting to chat server: %v", err)
+	}
+	err = c.sendClientInfo()
+	if err != nil {
+		return fmt.Errorf("error sending client info: %v", err)
+	}
+	stream, err := c.client.ReceiveMessages(context.Background(), &pb.ReceiveRequest{ClientId: c.Id.String()})
+	if err != nil {
+		return fmt.Errorf("error subscribing to messages: %v", err)
+	}
+	// make sure we know about ourselves before anything is displayed
+	c.pollConnectedClients()
+
+	// sending to server
+	go func() {
+		for {
+			var msg string
+			select {
+			case msg = <-c.sendCh:
+			case <-c.done:
+				return
+			}
+			recipientId := c.GetCurrentRecipientId()
+			if name, text, ok := strings.Cut(msg, " "); ok && strings.HasPrefix(name, "@") {
+				// "@bob hi" goes to bob whoever is selected
+				resp, err := c.client.ResolveUser(context.Background(), &pb.ResolveUserRequest{Name: name})
+				if err != nil {
+					log.Printf("could not resolve %s: %v", name, err)
+					continue
+				}
+				id, err := uuid.Parse(resp.GetClient().GetId())
+				if err != nil {
+					log.Printf("could not resolve %s: %v", name, err)
+					continue
+				}
+				recipientId, msg = &id, text
+			}
+			if recipientId == nil {
+				log.Printf("no recipient selected, dropping message: %s", msg)
+				continue
+			}
+			m := pb.ChatMessage{SenderId: c.Id.String(), Text: msg, RecipientId: recipientId.String()}
+			log.Printf("Sending message (from %s) to %s: %s", m.SenderId, m.RecipientId, m.Text)
+			_, err := c.client.Message(context.Background(), &m)
+			if err != nil {
+				log.Printf("could not send message: %v", err)
+				continue
+			}
+			// server does not echo our own messages back
+			c.deliver(&m)
+		}
+	}()
+
+	// receives messages from the server
+	go func() {
+		for {
+			m, err := stream.Recv()
+			if err == io.EOF {
+				log.Printf("server closed message stream")
+				close(c.done)
+				return
+			}
+			if err != nil {
+				log.Printf("error reading message from stream: %v", err)
+				close(c.done)
+				return
+			}
+			if id, err := uuid.Parse(m.SenderId); err == nil {
+				if _, err := c.FindById(id); err != nil {
+					// sender connected since our last poll
+					c.pollConnectedClients()
+				}
+			}
+			c.deliver(m)
+		}
+	}()
+
+	// server does not push connected clients, so poll for them
+	go func() {
+		t := time.NewTicker(ConnectedClientsPollInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				c.pollConnectedClients()
+			case <-c.done:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (c *Chat) pollConnectedClients() {
+	resp, err := c.client.GetConnectedClients(context.Background(), &pb.ConnectedClientsRequest{})
+	if err != nil {
+		log.Printf("could not get connected clients: %v", err)
+		return
+	}
+	c.deliver(resp)
+}
+
+func (c *Chat) connectToChatServer() error {
+	conn, err := grpc.Dial(ServerURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("could not dial to grpc server: %v", err)
+	}
+	c.conn = conn
+	c.client = pb.NewChatServerClient(conn)
+	return nil
+}
+
+func (c *Chat) sendClientInfo() error {
+	resp, err := c.client.Connect(context.Background(), &pb.ConnectRequest{Name: c.Name})
+	if err != nil {
+		return fmt.Errorf("could not connect: %v", err)
+	}
+	id, err := uuid.Parse(resp.GetClientId())
+	if err != nil {
+		return fmt.Errorf("server returned invalid client id: %v", err)
+	}
+	c.Id = id
+	return nil
+}
+
+func (c *Chat) Close() error {
+	if c.Id != uuid.Nil {
+		// otherwise the server keeps us around for a while, in case we
+		// come back
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if _, err := c.client.Disconnect(ctx, &pb.DisconnectRequest{ClientId: c.Id.String()}); err != nil {
+			log.Printf("could not disconnect: %v", err)
+		}
+	}
+	return c.conn.Close()
+}
+
+func (c *Chat) SetCurrentRecipientId(id uuid.UUID) {
+	c.currentRecipientMu.Lock()
+	defer c.currentRecipientMu.Unlock()
+	c.currentRecipientId = &id
+}
+
+func (c *Chat) GetCurrentRecipientId() *uuid.UUID {
+	c.currentRecipientMu.Lock()
+	defer c.currentRecipientMu.Unlock()
+	return c.currentRecipientId
+}
+
+type ConnectedClient struct {
+	unreadMu sync.Mutex
+	unread   int
+
+	id     uuid.UUID
+	name   string
+	status string
+}
+
+func (c *ConnectedClient) DisplayName() string {
+	if c.unread > 0 {
+		return fmt.Sprintf("%s (%d)", c.name, c.unread)
+	}
+	return c.name
+}
+
+func (c *ConnectedClient) GetUnread() int {
+	c.unreadMu.Lock()
+	defer c.unreadMu.Unlock()
+
+	return c.unread
+}
+
+func (c *ConnectedClient) IncUnread() int {
+	c.unreadMu.Lock()
+	defer c.unreadMu.Unlock()
+	c.unread += 1
+	return c.unread
+}
+
+func (c *ConnectedClient) ZeroUnread() {
+	c.unreadMu.Lock()
+	defer c.unreadMu.Unlock()
+	c.unread = 0
+}
+
+type ChatEntry struct {
+	Sender    string
+	Recipient string
+	Text      string
+	Time      time.Time
+
+	// names change, ids do not
+	senderId    uuid.UUID
+	recipientId uuid.UUID
+}
+
+func (e *ChatEntry) Format() string {
+	return fmt.Sprintf("%s %s: %s", e.Time.Format("15:04"), e.Sender, e.Text)
+}
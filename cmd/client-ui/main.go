@@ -10,7 +10,7 @@ import (
 	"github.com/gdamore/tcell/v2"
 	"github.com/google/uuid"
 
-	"github.com/wmolicki/go-chat/pkg/message"
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
 )
 
 const ServerURL = "localhost:8081"
@@ -32,35 +32,45 @@ func parseFlags() Config {
 	return c
 }
 
-func handleChatMessage(chat *Chat, tui *TUI, m *message.ChatMessage) error {
-	e := chat.AppendHistory(m.SenderId, m.RecipientId, m.Text)
-	if *chat.GetCurrentRecipientId() == m.SenderId || *chat.GetCurrentRecipientId() == m.RecipientId {
-		tui.app.QueueUpdateDraw(func() {
+func handleChatMessage(chat *Chat, tui *TUI, m *pb.ChatMessage) error {
+	senderId, err := uuid.Parse(m.SenderId)
+	if err != nil {
+		return fmt.Errorf("invalid sender id: %v", err)
+	}
+	recipientId, err := uuid.Parse(m.RecipientId)
+	if err != nil {
+		return fmt.Errorf("invalid recipient id: %v", err)
+	}
+	// history is appended on the ui goroutine so that it is ordered
+	// after any pending connected clients update
+	tui.app.QueueUpdateDraw(func() {
+		e := chat.AppendHistory(senderId, recipientId, m.Text)
+		current := chat.GetCurrentRecipientId()
+		if current != nil && (*current == senderId || *current == recipientId) {
 			tui.history.AddItem(e.Format(), "", 0, func() {})
-		})
-	} else {
+			return
+		}
 		// new message in hidden chat, add the "new message" indicator
-		tui.app.QueueUpdateDraw(func() {
-			var targetId uuid.UUID
-			if m.SenderId == chat.Id {
-				// messages sent by me are saved in chat history with recepient
-				targetId = m.RecipientId
-			} else {
-				// messages sent to me are saved in chat history of the sender
-				targetId = m.SenderId
-			}
-			targetClient, err := chat.FindById(targetId)
-			if err != nil {
-				log.Printf("hidden message for unknown client: %s: %s\n", targetId, err)
-			}
-			targetClient.IncUnread()
-			tui.updateRecipientNameInChat(chat, &targetId)
-		})
-	}
+		var targetId uuid.UUID
+		if senderId == chat.Id {
+			// messages sent by me are saved in chat history with recepient
+			targetId = recipientId
+		} else {
+			// messages sent to me are saved in chat history of the sender
+			targetId = senderId
+		}
+		targetClient, err := chat.FindById(targetId)
+		if err != nil {
+			log.Printf("hidden message for unknown client: %s: %s\n", targetId, err)
+			return
+		}
+		targetClient.IncUnread()
+		tui.updateRecipientNameInChat(chat, &targetId)
+	})
 	return nil
 }
 
-func handleConnectedClientsMessage(chat *Chat, tui *TUI, m *message.ConnectedClientsMessage) error {
+func handleConnectedClientsMessage(chat *Chat, tui *TUI, m *pb.ConnectedClientsResponse) error {
 	tui.app.QueueUpdateDraw(func() {
 		sort.Slice(m.Clients, func(i, j int) bool {
 			return m.Clients[i].Name < m.Clients[j].Name
@@ -69,26 +79,50 @@ func handleConnectedClientsMessage(chat *Chat, tui *TUI, m *message.ConnectedCli
 		// not a copy
 		currentlyConnected := chat.GetConnectedClients()
 		currentlyConnectedCopy := make(map[uuid.UUID]struct{})
-		for k, _ := range currentlyConnected {
+		chat.connectedClientsMu.Lock()
+		for k := range currentlyConnected {
 			currentlyConnectedCopy[k] = struct{}{}
 		}
+		chat.connectedClientsMu.Unlock()
 		serverConnected := make(map[uuid.UUID]struct{})
 
-		chat.SetConnectedClients(m.Clients)
+		renamed := chat.SetConnectedClients(m.Clients)
+		for id, old := range renamed {
+			c, err := chat.FindById(id)
+			if err != nil {
+				continue
+			}
+			log.Printf("%s is now known as %s\n", old, c.name)
+			chat.Rename(id, c.name)
+			if id != chat.Id {
+				tui.updateRecipientNameInChat(chat, &id)
+			}
+		}
+		if current := chat.GetCurrentRecipientId(); current != nil {
+			if c, err := chat.FindById(*current); err == nil && len(renamed) > 0 {
+				// redraw the title and history labels
+				tui.setCurrentRecipient(chat, c.DisplayName())
+			} else if err == nil {
+				// the status may have changed
+				tui.setTitle(chat, c)
+			}
+		}
 
 		// new connected cliens
 		for _, c := range m.Clients {
+			id, err := uuid.Parse(c.Id)
+			if err != nil {
+				continue
+			}
 			// dont display my own username in chat
-			if _, ok := currentlyConnectedCopy[c.Id]; !ok && c.Name != chat.Name {
+			if _, ok := currentlyConnectedCopy[id]; !ok && id != chat.Id {
 				// not in currently connected, need to add
-				tui.connected.AddItem(c.Name, c.Id.String(), 0, nil)
-			} else if c.Name == chat.Name {
-				chat.Id = c.Id
+				tui.connected.AddItem(c.Name, c.Id, 0, nil)
 			}
-			serverConnected[c.Id] = struct{}{}
+			serverConnected[id] = struct{}{}
 		}
 		// deleting no longer connected
-		for k, _ := range currentlyConnectedCopy {
+		for k := range currentlyConnectedCopy {
 			if _, ok := serverConnected[k]; !ok {
 				// not in server connected, need to delete
 				// (need to find by display name of client)
@@ -102,7 +136,7 @@ func handleConnectedClientsMessage(chat *Chat, tui *TUI, m *message.ConnectedCli
 
 		// first-time population of currentRecepientId when there is at
 		// least one client connected
-		if chat.currentRecipientId == nil && tui.connected.GetItemCount() > 0 {
+		if chat.GetCurrentRecipientId() == nil && tui.connected.GetItemCount() > 0 {
 			username, _ := tui.connected.GetItemText(tui.connected.GetCurrentItem())
 			tui.setCurrentRecipient(chat, username)
 		}
@@ -172,12 +206,12 @@ func main() {
 		case tcell.KeyRune:
 			switch e.Rune() {
 			case 'j':
-				if tui.connected.HasFocus() {
-					tui.connected.SetCurrentItem((tui.connected.GetCurrentItem() + 1) % tui.connected.GetItemCount())
+				if n := tui.connected.GetItemCount(); tui.connected.HasFocus() && n > 0 {
+					tui.connected.SetCurrentItem((tui.connected.GetCurrentItem() + 1) % n)
 				}
 			case 'k':
-				if tui.connected.HasFocus() {
-					tui.connected.SetCurrentItem((tui.connected.GetCurrentItem() - 1) % tui.connected.GetItemCount())
+				if n := tui.connected.GetItemCount(); tui.connected.HasFocus() && n > 0 {
+					tui.connected.SetCurrentItem((tui.connected.GetCurrentItem() - 1 + n) % n)
 				}
 			}
 		}
@@ -191,24 +225,19 @@ func main() {
 			if !ok {
 				log.Fatalf("message channel closed - this is not intended atm")
 			}
-			decoded, err := message.Decode(msg)
-			switch m := decoded.(type) {
-			case *message.ChatMessage:
+			switch m := msg.(type) {
+			case *pb.ChatMessage:
 				log.Printf("Received message from %s to %s: %s", m.SenderId, m.RecipientId, m.Text)
 				err := handleChatMessage(chat, tui, m)
 				if err != nil {
 					fmt.Printf("error handling message: %s\n", err)
 				}
-			case *message.ConnectedClientsMessage:
+			case *pb.ConnectedClientsResponse:
 				err := handleConnectedClientsMessage(chat, tui, m)
 				if err != nil {
 					fmt.Printf("error handling message: %s\n", err)
 				}
 			}
-			if err != nil {
-				log.Printf("could not decode message: %v, skipping", err)
-				continue
-			}
 		}
 	}()
 
@@ -87,13 +87,13 @@ func NewTUI() *TUI {
 func (tui *TUI) setCurrentRecipient(chat *Chat, username string) {
 	connClient, err := chat.Find(username)
 	if err != nil {
-		fmt.Errorf("connected error: %s", err)
+		log.Printf("connected error: %s", err)
 		return
 	}
 	chat.SetCurrentRecipientId(connClient.id)
 	log.Printf("connected changed to %s (%s)\n", username, chat.GetCurrentRecipientId())
 
-	tui.history.SetTitle(fmt.Sprintf("(%s) Chatting with %s", chat.Name, connClient.name))
+	tui.setTitle(chat, connClient)
 	tui.history.Clear()
 	entries := chat.GetHistoryFor(connClient.id)
 	for _, e := range entries {
@@ -101,3 +101,11 @@ func (tui *TUI) setCurrentRecipient(chat *Chat, username string) {
 	}
 	connClient.ZeroUnread()
 }
+
+func (tui *TUI) setTitle(chat *Chat, connClient *ConnectedClient) {
+	if connClient.status != "" {
+		tui.history.SetTitle(fmt.Sprintf("(%s) Chatting with %s - %s", chat.Name, connClient.name, connClient.status))
+		return
+	}
+	tui.history.SetTitle(fmt.Sprintf("(%s) Chatting with %s", chat.Name, connClient.name))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wmolicki/go-chat/pkg/bot"
+	"github.com/wmolicki/go-chat/pkg/client"
+)
+
+const (
+	maxDice  = 100
+	maxSides = 1000
+)
+
+// parseDice parses NdM notation, "d20" meaning a single die.
+func parseDice(s string) (n, sides int, err error) {
+	count, size, ok := strings.Cut(strings.ToLower(s), "d")
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: %q is not in NdM notation", bot.ErrUsage, s)
+	}
+	n = 1
+	if count != "" {
+		if n, err = strconv.Atoi(count); err != nil {
+			return 0, 0, fmt.Errorf("%w: bad dice count %q", bot.ErrUsage, count)
+		}
+	}
+	if sides, err = strconv.Atoi(size); err != nil {
+		return 0, 0, fmt.Errorf("%w: bad number of sides %q", bot.ErrUsage, size)
+	}
+	if n < 1 || n > maxDice || sides < 2 || sides > maxSides {
+		return 0, 0, fmt.Errorf("%w: between 1 and %d dice with 2 to %d sides", bot.ErrUsage, maxDice, maxSides)
+	}
+	return n, sides, nil
+}
+
+func roll(ctx context.Context, req *bot.Request) error {
+	n, sides := 1, 6
+	if len(req.Args) == 1 {
+		var err error
+		if n, sides, err = parseDice(req.Args[0]); err != nil {
+			return err
+		}
+	}
+	rolls := make([]string, n)
+	total := 0
+	for i := range rolls {
+		r := rand.Intn(sides) + 1
+		total += r
+		rolls[i] = strconv.Itoa(r)
+	}
+	return req.Replyf(ctx, "%s rolled %dd%d: %s = %d", req.From.Name, n, sides, strings.Join(rolls, " + "), total)
+}
+
+func echo(ctx context.Context, req *bot.Request) error {
+	return req.Reply(ctx, req.Text)
+}
+
+func main() {
+	addr := flag.String("server", "localhost:8081", "chat server address")
+	name := flag.String("name", "dicebot", "bot username")
+	flag.Parse()
+
+	rand.Seed(time.Now().UnixNano())
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	c, err := client.Dial(ctx, *addr, *name, client.WithLogger(log.Default()))
+	if err != nil {
+		log.Fatalf("could not connect: %v", err)
+	}
+	defer c.Close()
+
+	b := bot.New(c)
+	b.Handle(bot.Command{Name: "echo", Usage: "<text>", Help: "repeat text back", MinArgs: 1, Raw: true, Handler: echo})
+	b.Handle(bot.Command{Name: "roll", Usage: "[NdM]", Help: "roll N dice with M sides, 1d6 by default", MaxArgs: 1, Handler: roll})
+
+	log.Printf("%s connected to %s\n", *name, *addr)
+	if err := b.Run(ctx); err != nil && err != context.Canceled {
+		log.Fatalf("bot stopped: %v", err)
+	}
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	BrokerQueueSize  = 10000
+	BrokerBackoff    = 100 * time.Millisecond
+	BrokerMaxBackoff = 5 * time.Second
+)
+
+// topicCluster carries clients, rooms and node events. Every node is
+// subscribed to it.
+const topicCluster = "cluster"
+
+func userTopic(id uuid.UUID) string {
+	return "user." + id.String()
+}
+
+func roomTopic(name string) string {
+	return "room." + name
+}
+
+// Broker routes envelopes between server replicas. A node subscribes to
+// the topics of the users and rooms it has streams for, envelopes it
+// publishes reach every node subscribed to the topic, itself included.
+type Broker interface {
+	Publish(env *pb.BrokerEnvelope) error
+	Subscribe(topic string) error
+	Unsubscribe(topic string) error
+	// Envelopes delivers what was published to the subscribed topics.
+	Envelopes() <-chan *pb.BrokerEnvelope
+	Close() error
+}
+
+// memoryHub connects the nodes of one process. It also backs the hub of
+// the networked broker.
+type memoryHub struct {
+	log   hclog.Logger
+	mu    sync.RWMutex
+	nodes map[*memoryBroker]struct{}
+}
+
+func newMemoryHub(log hclog.Logger) *memoryHub {
+	return &memoryHub{log: log, nodes: make(map[*memoryBroker]struct{})}
+}
+
+// attach adds a node and tells everyone, the node included, about it.
+func (h *memoryHub) attach(node string) *memoryBroker {
+	b := &memoryBroker{
+		hub:       h,
+		node:      node,
+		topics:    map[string]bool{topicCluster: true},
+		envelopes: make(chan *pb.BrokerEnvelope, BrokerQueueSize),
+	}
+	h.mu.Lock()
+	h.nodes[b] = struct{}{}
+	h.mu.Unlock()
+	h.publish(&pb.BrokerEnvelope{Topic: topicCluster, Payload: &pb.BrokerEnvelope_NodeEvent{
+		NodeEvent: &pb.NodeEvent{Kind: pb.NodeEvent_HELLO, Node: node},
+	}})
+	return b
+}
+
+func (h *memoryHub) publish(env *pb.BrokerEnvelope) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for b := range h.nodes {
+		if b.subscribed(env.GetTopic()) {
+			select {
+			case b.envelopes <- env:
+			default:
+				h.log.Warn("broker queue full, dropping envelope", "node", b.node, "topic", env.GetTopic())
+				messagesDropped.WithLabelValues("broker").Inc()
+			}
+		}
+	}
+}
+
+type memoryBroker struct {
+	hub  *memoryHub
+	node string
+
+	mu        sync.Mutex
+	topics    map[string]bool
+	envelopes chan *pb.BrokerEnvelope
+}
+
+func (b *memoryBroker) subscribed(topic string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.topics[topic]
+}
+
+func (b *memoryBroker) Publish(env *pb.BrokerEnvelope) error {
+	b.hub.publish(env)
+	return nil
+}
+
+func (b *memoryBroker) Subscribe(topic string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.topics[topic] = true
+	return nil
+}
+
+func (b *memoryBroker) Unsubscribe(topic string) error {
+	if topic == topicCluster {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.topics, topic)
+	return nil
+}
+
+func (b *memoryBroker) Envelopes() <-chan *pb.BrokerEnvelope {
+	return b.envelopes
+}
+
+// Close detaches the node and tells the others it is gone.
+func (b *memoryBroker) Close() error {
+	b.hub.mu.Lock()
+	_, ok := b.hub.nodes[b]
+	delete(b.hub.nodes, b)
+	b.hub.mu.Unlock()
+	if ok {
+		b.hub.publish(&pb.BrokerEnvelope{Topic: topicCluster, Payload: &pb.BrokerEnvelope_NodeEvent{
+			NodeEvent: &pb.NodeEvent{Kind: pb.NodeEvent_DOWN, Node: b.node},
+		}})
+	}
+	return nil
+}
+
+// brokerHub serves the Broker service, attaching each remote node to a
+// memoryHub shared with the node of this process.
+type brokerHub struct {
+	pb.UnimplementedBrokerServer
+	hub    *memoryHub
+	secret string
+	log    hclog.Logger
+	// attached nodes are let go once it is closed
+	goingAway <-chan struct{}
+}
+
+func (h *brokerHub) Attach(stream pb.Broker_AttachServer) error {
+	if err := authorizeReplica(stream.Context(), h.secret); err != nil {
+		return err
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	node := first.GetAttach()
+	if node == "" {
+		return status.Errorf(codes.InvalidArgument, "first frame must attach")
+	}
+	if err := stream.SendHeader(metadata.Pairs(subscribedHeader, "true")); err != nil {
+		return err
+	}
+
+	b := h.hub.attach(node)
+	defer b.Close()
+	h.log.Info("node attached", "node", node)
+	defer h.log.Info("node detached", "node", node)
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case env := <-b.Envelopes():
+				if err := stream.Send(env); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	received := make(chan error, 1)
+	go func() {
+		for {
+			frame, err := stream.Recv()
+			if err != nil {
+				received <- err
+				return
+			}
+			switch f := frame.GetFrame().(type) {
+			case *pb.BrokerFrame_Subscribe:
+				b.Subscribe(f.Subscribe)
+			case *pb.BrokerFrame_Unsubscribe:
+				b.Unsubscribe(f.Unsubscribe)
+			case *pb.BrokerFrame_Publish:
+				b.Publish(f.Publish)
+			}
+		}
+	}()
+	select {
+	case err := <-received:
+		if err == io.EOF || ctx.Err() != nil {
+			return nil
+		}
+		return err
+	case <-h.goingAway:
+		return errGoingAway()
+	}
+}
+
+// authorizeReplica checks the bearer token of another replica when the
+// replicas share a secret.
+func authorizeReplica(ctx context.Context, secret string) error {
+	if secret == "" {
+		return nil
+	}
+	if !hasBearerToken(ctx, secret) {
+		return status.Errorf(codes.Unauthenticated, "invalid replica secret")
+	}
+	return nil
+}
+
+// hasBearerToken reports whether the caller sent secret as its bearer
+// token.
+func hasBearerToken(ctx context.Context, secret string) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	if v := md.Get("authorization"); len(v) > 0 && strings.HasPrefix(v[0], "Bearer ") {
+		token = strings.TrimPrefix(v[0], "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(token)) == 1
+}
+
+// grpcBroker attaches to the brokerHub of another replica. It reattaches
+// when the hub goes away, replaying its subscriptions.
+type grpcBroker struct {
+	node   string
+	secret string
+	log    hclog.Logger
+	conn   *grpc.ClientConn
+	api    pb.BrokerClient
+	cancel context.CancelFunc
+
+	// mu guards topics and stream and serializes sends
+	mu     sync.Mutex
+	topics map[string]bool
+	stream pb.Broker_AttachClient
+
+	envelopes chan *pb.BrokerEnvelope
+}
+
+func dialBroker(addr, node, secret string, creds grpc.DialOption, log hclog.Logger) (*grpcBroker, error) {
+	conn, err := grpc.Dial(addr, creds)
+	if err != nil {
+		return nil, fmt.Errorf("could not dial broker %s: %v", addr, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &grpcBroker{
+		node:      node,
+		secret:    secret,
+		log:       log,
+		conn:      conn,
+		api:       pb.NewBrokerClient(conn),
+		cancel:    cancel,
+		topics:    make(map[string]bool),
+		envelopes: make(chan *pb.BrokerEnvelope, BrokerQueueSize),
+	}
+	go b.run(ctx)
+	return b, nil
+}
+
+func (b *grpcBroker) run(ctx context.Context) {
+	backoff := BrokerBackoff
+	for {
+		attached, err := b.session(ctx)
+		b.mu.Lock()
+		b.stream = nil
+		b.mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		if attached {
+			backoff = BrokerBackoff
+		}
+		b.log.Warn("broker connection lost, reattaching", "error", err, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > BrokerMaxBackoff {
+			backoff = BrokerMaxBackoff
+		}
+	}
+}
+
+func (b *grpcBroker) session(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if b.secret != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+b.secret)
+	}
+	stream, err := b.api.Attach(ctx)
+	if err != nil {
+		return false, err
+	}
+	if err := stream.Send(&pb.BrokerFrame{Frame: &pb.BrokerFrame_Attach{Attach: b.node}}); err != nil {
+		return false, err
+	}
+	if err := waitSubscribed(stream, &pb.BrokerEnvelope{}); err != nil {
+		return false, err
+	}
+
+	b.mu.Lock()
+	for topic := range b.topics {
+		if err := stream.Send(&pb.BrokerFrame{Frame: &pb.BrokerFrame_Subscribe{Subscribe: topic}}); err != nil {
+			b.mu.Unlock()
+			return false, err
+		}
+	}
+	b.stream = stream
+	b.mu.Unlock()
+	b.log.Info("attached to broker", "node", b.node)
+
+	for {
+		env, err := stream.Recv()
+		if err != nil {
+			return true, err
+		}
+		select {
+		case b.envelopes <- env:
+		default:
+			b.log.Warn("broker queue full, dropping envelope", "topic", env.GetTopic())
+			messagesDropped.WithLabelValues("broker").Inc()
+		}
+	}
+}
+
+func (b *grpcBroker) send(frame *pb.BrokerFrame) error {
+	if b.stream == nil {
+		return status.Errorf(codes.Unavailable, "not attached to the broker")
+	}
+	return b.stream.Send(frame)
+}
+
+func (b *grpcBroker) Publish(env *pb.BrokerEnvelope) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.send(&pb.BrokerFrame{Frame: &pb.BrokerFrame_Publish{Publish: env}})
+}
+
+// Subscribe and Unsubscribe only fail to reach the hub while detached,
+// the subscriptions are replayed on reattach.
+func (b *grpcBroker) Subscribe(topic string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.topics[topic] {
+		return nil
+	}
+	b.topics[topic] = true
+	if b.stream == nil {
+		return nil
+	}
+	return b.send(&pb.BrokerFrame{Frame: &pb.BrokerFrame_Subscribe{Subscribe: topic}})
+}
+
+func (b *grpcBroker) Unsubscribe(topic string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.topics[topic] {
+		return nil
+	}
+	delete(b.topics, topic)
+	if b.stream == nil {
+		return nil
+	}
+	return b.send(&pb.BrokerFrame{Frame: &pb.BrokerFrame_Unsubscribe{Unsubscribe: topic}})
+}
+
+func (b *grpcBroker) Envelopes() <-chan *pb.BrokerEnvelope {
+	return b.envelopes
+}
+
+func (b *grpcBroker) Close() error {
+	b.cancel()
+	return b.conn.Close()
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Every replica keeps the clients and rooms of the whole cluster, clients
+// of other replicas have node set to the replica holding their stream.
+// Messages are published to the topic of their recipient or room and
+// queued by the replica subscribed to it. Changes to clients and rooms are
+// published on topicCluster, or go through the raft log in cluster mode,
+// see raft.go. Listeners only see the ones made locally.
+
+// isLocal reports whether c's stream is on this replica, so messages for
+// it go to its queues. It must be called with clientsMu held.
+func (s *server) isLocal(c *client) bool {
+	return c.peer == nil && c.node == s.node
+}
+
+func (s *server) publish(ctx context.Context, topic string, env *pb.BrokerEnvelope) (err error) {
+	env.Topic, env.Node = topic, s.node
+	defer observeStore("broker", "publish", time.Now())
+	_, span := tracer.Start(ctx, "broker publish", trace.WithAttributes(attribute.String("gochat.topic", topic)))
+	defer func() { endSpan(span, err) }()
+	if err := s.broker.Publish(env); err != nil {
+		return status.Errorf(codes.Unavailable, "could not publish: %v", err)
+	}
+	return nil
+}
+
+// publishChange shares a client or room change with the other replicas.
+func (s *server) publishChange(env *pb.BrokerEnvelope) error {
+	if s.replicator != nil {
+		env.Node = s.node
+		return s.replicator.propose(context.Background(), env)
+	}
+	return s.publish(context.Background(), topicCluster, env)
+}
+
+func (s *server) publishClient(c client, online bool) {
+	if c.peer != nil {
+		return
+	}
+	err := s.publishChange(&pb.BrokerEnvelope{Payload: &pb.BrokerEnvelope_Client{Client: &pb.ClusterClient{
+		Id:      c.clientId.String(),
+		Name:    c.name,
+		Node:    c.node,
+		Online:  online,
+		Profile: c.profile,
+	}}})
+	if err != nil {
+		s.clusterLog.Error("could not publish client", "client_id", c.clientId, "error", err)
+	}
+}
+
+func (s *server) publishRoomUpdate(room string, clientId uuid.UUID, change pb.RoomUpdate_Change, topic string) {
+	err := s.publishChange(&pb.BrokerEnvelope{Payload: &pb.BrokerEnvelope_Room{Room: &pb.RoomUpdate{
+		Room:     room,
+		ClientId: clientId.String(),
+		Change:   change,
+		Topic:    topic,
+	}}})
+	if err != nil {
+		s.clusterLog.Error("could not publish room update", "room", room, "error", err)
+	}
+}
+
+// syncRoomSubscription subscribes to the room while it has local members.
+func (s *server) syncRoomSubscription(name string) {
+	s.roomSubsMu.Lock()
+	defer s.roomSubsMu.Unlock()
+	s.clientsMu.Lock()
+	want := false
+	if r, ok := s.rooms[name]; ok {
+		for _, c := range r.members {
+			if s.isLocal(c) {
+				want = true
+				break
+			}
+		}
+	}
+	s.clientsMu.Unlock()
+
+	var err error
+	if want {
+		err = s.broker.Subscribe(roomTopic(name))
+	} else {
+		err = s.broker.Unsubscribe(roomTopic(name))
+	}
+	if err != nil {
+		s.clusterLog.Error("could not update room subscription", "room", name, "error", err)
+	}
+}
+
+// roomsOf must be called with clientsMu held.
+func (s *server) roomsOf(c *client) []string {
+	var names []string
+	for _, r := range s.rooms {
+		if _, ok := r.members[c.clientId]; ok {
+			names = append(names, r.name)
+		}
+	}
+	return names
+}
+
+// routeEnvelopes handles what the broker delivers until ctx is done.
+func (s *server) routeEnvelopes(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case env := <-s.broker.Envelopes():
+			s.handleEnvelope(env)
+		}
+	}
+}
+
+func (s *server) handleEnvelope(env *pb.BrokerEnvelope) {
+	switch p := env.GetPayload().(type) {
+	case *pb.BrokerEnvelope_Message:
+		m := chatMessageFromProto(p.Message)
+		m.trace = env.GetTrace()
+		s.deliverLocal(m)
+	case *pb.BrokerEnvelope_Client:
+		if env.GetNode() != s.node {
+			s.applyClusterClient(p.Client)
+		}
+	case *pb.BrokerEnvelope_Room:
+		if env.GetNode() != s.node {
+			s.applyRoomUpdate(p.Room)
+		}
+	case *pb.BrokerEnvelope_NodeEvent:
+		if s.replicator != nil {
+			// the raft log already has everything, replicas that are
+			// gone are reaped by the leader
+			return
+		}
+		switch ev := p.NodeEvent; ev.GetKind() {
+		case pb.NodeEvent_HELLO:
+			// either this replica (re)attached or another one did, both
+			// need to know what is here
+			s.announce()
+		case pb.NodeEvent_DOWN:
+			if ev.GetNode() != s.node {
+				s.dropNode(ev.GetNode())
+			}
+		}
+	}
+}
+
+// deliverLocal queues a published message for the clients of this replica.
+func (s *server) deliverLocal(m chatMessage) {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	if m.room != "" {
+		r, ok := s.rooms[m.room]
+		if !ok {
+			return
+		}
+		for id, c := range r.members {
+			if id.String() != m.sender && s.isLocal(c) {
+				s.enqueue(c, m)
+			}
+		}
+		return
+	}
+	id, err := uuid.Parse(m.recipient)
+	if err != nil {
+		return
+	}
+	if c, ok := s.clients[id]; ok && s.isLocal(c) {
+		s.enqueue(c, m)
+	}
+}
+
+func (s *server) applyClusterClient(cc *pb.ClusterClient) {
+	id, err := uuid.Parse(cc.GetId())
+	if err != nil {
+		return
+	}
+	s.clientsMu.Lock()
+	c, ok := s.clients[id]
+	if !cc.GetOnline() {
+		// ignore news from a replica that no longer holds the client
+		stale := !ok || c.node != cc.GetNode()
+		s.clientsMu.Unlock()
+		if !stale {
+			s.removeRemote(c)
+		}
+		return
+	}
+	if ok {
+		moved := s.isLocal(c) && cc.GetNode() != s.node
+		old := c.name
+		c.name, c.node = cc.GetName(), cc.GetNode()
+		if old != c.name {
+			// renamed on another replica
+			s.broadcastRename(c, old)
+		}
+		if !proto.Equal(c.profile, cc.GetProfile()) {
+			c.profile = cc.GetProfile()
+			s.broadcastPresence(c, pb.PresenceEvent_UPDATED)
+		}
+		rooms := s.roomsOf(c)
+		s.clientsMu.Unlock()
+		if moved {
+			// the client subscribed on another replica
+			s.broker.Unsubscribe(userTopic(id))
+			for _, name := range rooms {
+				s.syncRoomSubscription(name)
+			}
+		}
+		return
+	}
+	c = &client{clientId: id, name: cc.GetName(), node: cc.GetNode(), profile: cc.GetProfile()}
+	s.clients[id] = c
+	s.broadcastPresence(c, pb.PresenceEvent_ONLINE)
+	s.clientsMu.Unlock()
+}
+
+func (s *server) applyRoomUpdate(ru *pb.RoomUpdate) {
+	id, err := uuid.Parse(ru.GetClientId())
+	if err != nil {
+		return
+	}
+	name := ru.GetRoom()
+	s.clientsMu.Lock()
+	r, ok := s.rooms[name]
+	switch ru.GetChange() {
+	case pb.RoomUpdate_JOINED:
+		c, known := s.clients[id]
+		if !known {
+			break
+		}
+		if !ok {
+			r = &room{name: name, members: make(map[uuid.UUID]*client)}
+			s.rooms[name] = r
+		}
+		r.members[id] = c
+		if ru.GetTopic() != "" {
+			r.topic = ru.GetTopic()
+		}
+	case pb.RoomUpdate_LEFT:
+		if !ok {
+			// e.g. already dropped with the node that held it
+			break
+		}
+		if c, member := r.members[id]; member {
+			s.removeMember(r, c)
+		}
+	case pb.RoomUpdate_TOPIC:
+		if ok {
+			r.topic = ru.GetTopic()
+		}
+	}
+	s.clientsMu.Unlock()
+	s.syncRoomSubscription(name)
+}
+
+// announce publishes the clients of this replica and their rooms.
+func (s *server) announce() {
+	s.clientsMu.Lock()
+	var clients []client
+	for _, c := range s.clients {
+		if s.isLocal(c) {
+			clients = append(clients, *c)
+		}
+	}
+	type membership struct {
+		room, topic string
+		id          uuid.UUID
+	}
+	var memberships []membership
+	for _, r := range s.rooms {
+		for id, c := range r.members {
+			if s.isLocal(c) {
+				memberships = append(memberships, membership{r.name, r.topic, id})
+			}
+		}
+	}
+	s.clientsMu.Unlock()
+
+	for _, c := range clients {
+		s.publishClient(c, true)
+	}
+	for _, m := range memberships {
+		s.publishRoomUpdate(m.room, m.id, pb.RoomUpdate_JOINED, m.topic)
+	}
+}
+
+// dropNode removes the clients of a replica that went away.
+func (s *server) dropNode(node string) {
+	s.clientsMu.Lock()
+	var gone []*client
+	for _, c := range s.clients {
+		if c.peer == nil && c.node == node {
+			gone = append(gone, c)
+		}
+	}
+	s.clientsMu.Unlock()
+	if len(gone) > 0 {
+		s.clusterLog.Warn("node is gone, dropping its clients", "node", node, "clients", len(gone))
+	}
+	for _, c := range gone {
+		s.removeRemote(c)
+	}
+}
+
+// removeRemote removes a client of another replica without telling the
+// listeners, that replica already did.
+func (s *server) removeRemote(c *client) {
+	if _, left, ok := s.removeClient(c); ok {
+		for _, name := range left {
+			s.syncRoomSubscription(name)
+		}
+	}
+}
+
+// restoreCluster replaces the clients and rooms of other replicas with the
+// ones of a raft snapshot. Clients of this replica are left as they are.
+func (s *server) restoreCluster(st *pb.ClusterState) {
+	known := make(map[uuid.UUID]bool)
+	for _, cc := range st.GetClients() {
+		if cc.GetNode() == s.node {
+			// either held here already or left from an earlier run
+			continue
+		}
+		if id, err := uuid.Parse(cc.GetId()); err == nil {
+			known[id] = true
+			s.applyClusterClient(cc)
+		}
+	}
+
+	s.clientsMu.Lock()
+	var gone []*client
+	for id, c := range s.clients {
+		if c.peer == nil && !s.isLocal(c) && !known[id] {
+			gone = append(gone, c)
+		}
+	}
+	s.clientsMu.Unlock()
+	for _, c := range gone {
+		s.removeRemote(c)
+	}
+
+	s.clientsMu.Lock()
+	touched := make(map[string]bool)
+	for _, r := range s.rooms {
+		for _, c := range r.members {
+			if c.peer == nil && !s.isLocal(c) {
+				s.removeMember(r, c)
+				touched[r.name] = true
+			}
+		}
+	}
+	for _, rs := range st.GetRooms() {
+		for _, member := range rs.GetMemberIds() {
+			id, err := uuid.Parse(member)
+			if err != nil || !known[id] {
+				continue
+			}
+			r, ok := s.rooms[rs.GetName()]
+			if !ok {
+				r = &room{name: rs.GetName(), members: make(map[uuid.UUID]*client)}
+				s.rooms[r.name] = r
+			}
+			r.members[id] = s.clients[id]
+			r.topic = rs.GetTopic()
+			touched[r.name] = true
+		}
+	}
+	s.clientsMu.Unlock()
+	for name := range touched {
+		s.syncRoomSubscription(name)
+	}
+}
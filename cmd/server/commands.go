@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const commandPrefix = "/"
+
+// commandRequest is a slash command typed by caller, either in a room or
+// in a conversation with recipient.
+type commandRequest struct {
+	caller    *client
+	room      string
+	recipient string
+	args      string
+}
+
+// commandFunc executes a command. Its output is returned to the caller only.
+type commandFunc func(ctx context.Context, s *server, req commandRequest) (string, error)
+
+type command struct {
+	name  string
+	usage string
+	help  string
+	run   commandFunc
+}
+
+type commandRegistry struct {
+	mu       sync.RWMutex
+	commands map[string]*command
+}
+
+func newCommandRegistry() *commandRegistry {
+	return &commandRegistry{commands: make(map[string]*command)}
+}
+
+func (r *commandRegistry) register(cmd *command) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.commands[cmd.name]; ok {
+		return fmt.Errorf("command %s%s already registered", commandPrefix, cmd.name)
+	}
+	r.commands[cmd.name] = cmd
+	return nil
+}
+
+func (r *commandRegistry) lookup(name string) (*command, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cmd, ok := r.commands[strings.ToLower(name)]
+	return cmd, ok
+}
+
+// list returns the registered commands sorted by name.
+func (r *commandRegistry) list() []*command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cmds := make([]*command, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		cmds = append(cmds, cmd)
+	}
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].name < cmds[j].name })
+	return cmds
+}
+
+func (s *server) ListCommands(ctx context.Context, in *pb.ListCommandsRequest) (*pb.ListCommandsResponse, error) {
+	resp := &pb.ListCommandsResponse{}
+	for _, cmd := range s.commands.list() {
+		resp.Commands = append(resp.Commands, &pb.ListCommandsResponse_Command{Name: cmd.name, Usage: cmd.usage, Help: cmd.help})
+	}
+	return resp, nil
+}
+
+// lookupCommand returns the registered command text invokes, if any.
+// Unknown commands are delivered as regular messages so bots can handle
+// their own, text starting with "//" is delivered with one slash removed.
+func (s *server) lookupCommand(text string) (*command, bool) {
+	if !strings.HasPrefix(text, commandPrefix) || strings.HasPrefix(text, commandPrefix+commandPrefix) {
+		return nil, false
+	}
+	name, _, _ := strings.Cut(strings.TrimPrefix(text, commandPrefix), " ")
+	return s.commands.lookup(name)
+}
+
+func unescapeCommand(text string) string {
+	if strings.HasPrefix(text, commandPrefix+commandPrefix) {
+		return text[len(commandPrefix):]
+	}
+	return text
+}
+
+func (s *server) runCommand(ctx context.Context, cmd *command, caller *client, m chatMessage) (string, error) {
+	_, args, _ := strings.Cut(m.text, " ")
+	return cmd.run(ctx, s, commandRequest{caller: caller, room: m.room, recipient: m.recipient, args: strings.TrimSpace(args)})
+}
+
+func usageError(cmd string, usage string) error {
+	return status.Errorf(codes.InvalidArgument, "usage: %s%s %s", commandPrefix, cmd, usage)
+}
+
+// targetRoom returns the room named by the first argument if it starts
+// with '#', otherwise the room the command was typed in.
+func (req commandRequest) targetRoom() (name string, rest string, err error) {
+	if strings.HasPrefix(req.args, "#") {
+		name, rest, _ = strings.Cut(req.args, " ")
+		return name, strings.TrimSpace(rest), nil
+	}
+	if req.room == "" {
+		return "", req.args, status.Errorf(codes.InvalidArgument, "no room given and not typed in a room")
+	}
+	return req.room, req.args, nil
+}
+
+func registerBuiltinCommands(r *commandRegistry) {
+	builtins := []*command{
+		{name: "me", usage: "<action>", help: "send an action, e.g. /me waves", run: cmdMe},
+		{name: "nick", usage: "<name>", help: "change your name", run: cmdNick},
+		{name: "status", usage: "[text]", help: "set your status, or clear it", run: cmdStatus},
+		{name: "join", usage: "<#room>", help: "join a room, creating it if needed", run: cmdJoin},
+		{name: "leave", usage: "[#room]", help: "leave a room", run: cmdLeave},
+		{name: "topic", usage: "[#room] [topic]", help: "show or set the room topic", run: cmdTopic},
+		{name: "who", usage: "[#room]", help: "list room members or everyone connected", run: cmdWho},
+		{name: "msg", usage: "<name> <text>", help: "send a private message", run: cmdMsg},
+	}
+	for _, cmd := range builtins {
+		if err := r.register(cmd); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func cmdMe(ctx context.Context, s *server, req commandRequest) (string, error) {
+	if req.args == "" {
+		return "", usageError("me", "<action>")
+	}
+	if req.room == "" && req.recipient == "" {
+		return "", status.Errorf(codes.InvalidArgument, "/me needs a room or recipient")
+	}
+	s.clientsMu.Lock()
+	// the name changes with /nick
+	name := req.caller.name
+	s.clientsMu.Unlock()
+	text := fmt.Sprintf("* %s %s", name, req.args)
+	return "", s.send(ctx, chatMessage{sender: req.caller.clientId.String(), recipient: req.recipient, room: req.room, text: text})
+}
+
+func cmdNick(ctx context.Context, s *server, req commandRequest) (string, error) {
+	if req.args == "" || strings.ContainsAny(req.args, " \t\n") {
+		return "", usageError("nick", "<name>")
+	}
+	if err := validateName("name", req.args); err != nil {
+		return "", err
+	}
+	old, err := s.rename(req.caller, req.args)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s is now known as %s", old, req.args), nil
+}
+
+func cmdStatus(ctx context.Context, s *server, req commandRequest) (string, error) {
+	fields := []string{"status"}
+	p := &pb.Profile{Status: req.args}
+	if err := validateProfile(p, fields, time.Now()); err != nil {
+		return "", err
+	}
+	s.updateProfile(req.caller, func(cur *pb.Profile) *pb.Profile {
+		return mergeProfile(cur, p, fields)
+	})
+	if req.args == "" {
+		return "status cleared", nil
+	}
+	return fmt.Sprintf("status set to %q", req.args), nil
+}
+
+// rename sets the name of c and returns the old one, unless another
+// client goes by name. Everyone is told about the new name, c keeps its
+// id.
+func (s *server) rename(c *client, name string) (string, error) {
+	s.clientsMu.Lock()
+	if s.nameTaken(name, c) {
+		s.clientsMu.Unlock()
+		return "", errNameTaken(name)
+	}
+	old := c.name
+	if old == name {
+		s.clientsMu.Unlock()
+		return old, nil
+	}
+	c.name = name
+	s.broadcastRename(c, old)
+	renamed := *c
+	s.clientsMu.Unlock()
+	s.publishClient(renamed, true)
+	s.emit(serverEvent{kind: clientRenamed, client: renamed, oldName: old})
+	return old, nil
+}
+
+func cmdJoin(ctx context.Context, s *server, req commandRequest) (string, error) {
+	if req.args == "" {
+		return "", usageError("join", "<#room>")
+	}
+	r, err := s.joinRoom(req.caller, req.args)
+	if err != nil {
+		return "", err
+	}
+	if r.topic == "" {
+		return fmt.Sprintf("joined %s", r.name), nil
+	}
+	return fmt.Sprintf("joined %s, topic: %s", r.name, r.topic), nil
+}
+
+func cmdLeave(ctx context.Context, s *server, req commandRequest) (string, error) {
+	name, _, err := req.targetRoom()
+	if err != nil {
+		return "", err
+	}
+	if err := s.leaveRoom(req.caller, name); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("left %s", name), nil
+}
+
+func cmdTopic(ctx context.Context, s *server, req commandRequest) (string, error) {
+	name, topic, err := req.targetRoom()
+	if err != nil {
+		return "", err
+	}
+	if topic == "" {
+		r, err := s.getRoom(name)
+		if err != nil {
+			return "", err
+		}
+		if r.topic == "" {
+			return fmt.Sprintf("%s has no topic", r.name), nil
+		}
+		return fmt.Sprintf("%s topic: %s", r.name, r.topic), nil
+	}
+	if err := s.setTopic(req.caller, name, topic); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("topic set to: %s", topic), nil
+}
+
+func cmdWho(ctx context.Context, s *server, req commandRequest) (string, error) {
+	if req.args == "" && req.room == "" {
+		s.clientsMu.Lock()
+		names := make([]string, 0, len(s.clients))
+		for _, c := range s.clients {
+			names = append(names, c.name)
+		}
+		s.clientsMu.Unlock()
+		sort.Strings(names)
+		return fmt.Sprintf("connected: %s", strings.Join(names, ", ")), nil
+	}
+	name, _, err := req.targetRoom()
+	if err != nil {
+		return "", err
+	}
+	r, err := s.getRoom(name)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s: %s", r.name, strings.Join(r.memberNames(), ", ")), nil
+}
+
+func cmdMsg(ctx context.Context, s *server, req commandRequest) (string, error) {
+	name, text, _ := strings.Cut(req.args, " ")
+	text = strings.TrimSpace(text)
+	if name == "" || text == "" {
+		return "", usageError("msg", "<name> <text>")
+	}
+	recipient, err := s.getClientByName(name)
+	if err != nil {
+		return "", err
+	}
+	return "", s.send(ctx, chatMessage{sender: req.caller.clientId.String(), recipient: recipient.clientId.String(), text: text})
+}
@@ -0,0 +1,529 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ListenAddr       = "localhost:8081"
+	MessageQueueSize = 100
+	EventQueueSize   = 100
+)
+
+// Defaults of settings only read through the config.
+const (
+	// characters a message may have
+	DefaultMaxMessageLength = 4000
+	// relayed message ids are remembered this long to drop retried duplicates
+	DefaultFederationDedupeWindow = 10 * time.Minute
+	// how long streams get to send what is queued for their clients once
+	// the server is asked to stop
+	DefaultShutdownTimeout = 10 * time.Second
+)
+
+// configEnvPrefix is prepended to the flag names, upper cased and with
+// dashes as underscores, to get the environment variable of a setting,
+// e.g. GOCHAT_RAFT_ADDR for -raft-addr.
+const configEnvPrefix = "GOCHAT_"
+
+// config is the effective configuration of the server: the defaults,
+// overridden by the file given with -config, then by environment
+// variables and then by flags. SIGHUP or the Admin Reload RPC read it
+// again, see liveSettings for what changes without a restart.
+type config struct {
+	// name of this replica, random when empty
+	Node      string            `yaml:"node"`
+	Listen    listenSettings    `yaml:"listen"`
+	TLS       tlsSettings       `yaml:"tls"`
+	Storage   storageSettings   `yaml:"storage"`
+	Broker    brokerSettings    `yaml:"broker"`
+	Limits    limitSettings     `yaml:"limits"`
+	Retention retentionSettings `yaml:"retention"`
+	Features  featureSettings   `yaml:"features"`
+	Log       logSettings       `yaml:"log"`
+	Trace     traceSettings     `yaml:"trace"`
+	Admin     adminSettings     `yaml:"admin"`
+	// how long streams get to send what is queued on SIGINT or SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type listenSettings struct {
+	GRPC string `yaml:"grpc"`
+	// empty disables the listener
+	HTTP string `yaml:"http"`
+	IRC  string `yaml:"irc"`
+}
+
+// tlsSettings enable TLS on all listeners when a certificate is given.
+// Other replicas and federation peers are then dialed with TLS too,
+// verified against CAFile or the system roots.
+type tlsSettings struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	CAFile   string `yaml:"ca_file"`
+}
+
+// storageSettings pick where clients and rooms are kept: in memory on
+// each replica, or replicated through a raft log.
+type storageSettings struct {
+	Backend string       `yaml:"backend"`
+	Raft    raftSettings `yaml:"raft"`
+}
+
+type raftSettings struct {
+	Addr string `yaml:"addr"`
+	// raft/<node> when empty
+	Dir       string `yaml:"dir"`
+	Join      string `yaml:"join"`
+	Bootstrap bool   `yaml:"bootstrap"`
+}
+
+type brokerSettings struct {
+	// grpc address of the replica serving the broker, in-process when
+	// empty
+	Addr   string `yaml:"addr"`
+	Hub    bool   `yaml:"hub"`
+	Secret string `yaml:"secret"`
+}
+
+type limitSettings struct {
+	MessageQueue     int `yaml:"message_queue"`
+	EventQueue       int `yaml:"event_queue"`
+	MaxMessageLength int `yaml:"max_message_length"`
+	// messages a connection, a user over all its connections and all
+	// senders to a room may send
+	SessionRate rateLimit    `yaml:"session_rate"`
+	UserRate    rateLimit    `yaml:"user_rate"`
+	RoomRate    rateLimit    `yaml:"room_rate"`
+	Mute        muteSettings `yaml:"mute"`
+}
+
+// rateLimit is a token bucket refilled with Rate tokens a second, up to
+// Burst. A Rate of 0 disables the limit.
+type rateLimit struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+// muteSettings refuse every message of a connection for Duration once it
+// hit a rate limit After times within Window. An After of 0 disables
+// muting.
+type muteSettings struct {
+	After    int           `yaml:"after"`
+	Window   time.Duration `yaml:"window"`
+	Duration time.Duration `yaml:"duration"`
+}
+
+type retentionSettings struct {
+	FederationDedupe time.Duration `yaml:"federation_dedupe"`
+	RaftSnapshots    int           `yaml:"raft_snapshots"`
+	// clients of an unreachable replica are kept this long so they can
+	// resume on another one
+	StaleClients time.Duration `yaml:"stale_clients"`
+	// clients whose stream ended are kept this long so they can
+	// subscribe again, 0 disconnects them right away
+	DisconnectedClients time.Duration `yaml:"disconnected_clients"`
+}
+
+type featureSettings struct {
+	Middleware         stringList `yaml:"middleware"`
+	BannedWords        stringList `yaml:"banned_words"`
+	BlockProfanity     bool       `yaml:"block_profanity"`
+	Scripts            string     `yaml:"scripts"`
+	Webhooks           string     `yaml:"webhooks"`
+	WebhooksDeadLetter string     `yaml:"webhooks_dead_letter"`
+	Federation         string     `yaml:"federation"`
+	Integrations       string     `yaml:"integrations"`
+	Metrics            bool       `yaml:"metrics"`
+	Gateway            bool       `yaml:"gateway"`
+	Websocket          bool       `yaml:"websocket"`
+	WSOrigins          stringList `yaml:"ws_origins"`
+}
+
+type logSettings struct {
+	Format string      `yaml:"format"`
+	Level  string      `yaml:"level"`
+	Levels levelsValue `yaml:"levels"`
+}
+
+type traceSettings struct {
+	OTLP string `yaml:"otlp"`
+	File string `yaml:"file"`
+}
+
+type adminSettings struct {
+	// bearer token of Admin RPCs, which are refused when empty
+	Secret string `yaml:"secret"`
+}
+
+func defaultConfig() *config {
+	return &config{
+		Listen:  listenSettings{GRPC: ListenAddr},
+		Storage: storageSettings{Backend: "memory"},
+		Limits: limitSettings{
+			MessageQueue:     MessageQueueSize,
+			EventQueue:       EventQueueSize,
+			MaxMessageLength: DefaultMaxMessageLength,
+			SessionRate:      rateLimit{Rate: SessionRate, Burst: SessionBurst},
+			UserRate:         rateLimit{Rate: UserRate, Burst: UserBurst},
+			RoomRate:         rateLimit{Rate: RoomRate, Burst: RoomBurst},
+			Mute:             muteSettings{After: MuteAfter, Window: MuteWindow, Duration: MuteDuration},
+		},
+		Retention: retentionSettings{
+			FederationDedupe:    DefaultFederationDedupeWindow,
+			RaftSnapshots:       RaftSnapshotRetain,
+			StaleClients:        RaftClientTimeout,
+			DisconnectedClients: ResumeGrace,
+		},
+		Features: featureSettings{
+			Middleware:         stringList{"validate", "profanity", "links", "mentions", "scripts"},
+			WebhooksDeadLetter: "webhooks-dead-letter.jsonl",
+		},
+		Log:             logSettings{Format: "text", Level: "info", Levels: levelsValue{}},
+		ShutdownTimeout: DefaultShutdownTimeout,
+	}
+}
+
+// bind registers a flag for every setting, with its current value as the
+// default.
+func (c *config) bind(fs *flag.FlagSet) {
+	fs.StringVar(&c.Node, "node", c.Node, "name of this replica, random when empty")
+
+	fs.StringVar(&c.Listen.GRPC, "addr", c.Listen.GRPC, "address of the grpc listener")
+	fs.StringVar(&c.Listen.HTTP, "http-addr", c.Listen.HTTP, "address of the http listener, e.g. localhost:8082, disabled when empty")
+	fs.StringVar(&c.Listen.IRC, "irc-addr", c.Listen.IRC, "address of the IRC listener, disabled when empty")
+
+	fs.StringVar(&c.TLS.CertFile, "tls-cert", c.TLS.CertFile, "PEM certificate, enables TLS on all listeners")
+	fs.StringVar(&c.TLS.KeyFile, "tls-key", c.TLS.KeyFile, "PEM key of -tls-cert")
+	fs.StringVar(&c.TLS.CAFile, "tls-ca", c.TLS.CAFile, "PEM CA other replicas and peers are verified with, system roots when empty")
+
+	fs.StringVar(&c.Storage.Backend, "storage", c.Storage.Backend, "where clients and rooms are kept, memory or raft")
+	fs.StringVar(&c.Storage.Raft.Addr, "raft-addr", c.Storage.Raft.Addr, "host:port to replicate clients and rooms over raft on")
+	fs.StringVar(&c.Storage.Raft.Dir, "raft-dir", c.Storage.Raft.Dir, "directory of the raft log and snapshots, raft/<node> when empty")
+	fs.StringVar(&c.Storage.Raft.Join, "raft-join", c.Storage.Raft.Join, "grpc address of a replica of the raft cluster to join")
+	fs.BoolVar(&c.Storage.Raft.Bootstrap, "raft-bootstrap", c.Storage.Raft.Bootstrap, "start a new raft cluster with this replica")
+
+	fs.StringVar(&c.Broker.Addr, "broker", c.Broker.Addr, "grpc address of the replica serving the broker, in-process when empty")
+	fs.BoolVar(&c.Broker.Hub, "broker-hub", c.Broker.Hub, "serve the broker to other replicas")
+	fs.StringVar(&c.Broker.Secret, "broker-secret", c.Broker.Secret, "secret replicas attach to the broker and call each other's raft service with")
+
+	fs.IntVar(&c.Limits.MessageQueue, "message-queue", c.Limits.MessageQueue, "messages queued per client before new ones are dropped")
+	fs.IntVar(&c.Limits.EventQueue, "event-queue", c.Limits.EventQueue, "events queued per client before new ones are dropped")
+	fs.IntVar(&c.Limits.MaxMessageLength, "max-message-length", c.Limits.MaxMessageLength, "characters a message may have")
+	fs.Float64Var(&c.Limits.SessionRate.Rate, "session-rate", c.Limits.SessionRate.Rate, "messages a second one connection may send, 0 for no limit")
+	fs.IntVar(&c.Limits.SessionRate.Burst, "session-burst", c.Limits.SessionRate.Burst, "messages one connection may send at once")
+	fs.Float64Var(&c.Limits.UserRate.Rate, "user-rate", c.Limits.UserRate.Rate, "messages a second a user may send over all its connections, 0 for no limit")
+	fs.IntVar(&c.Limits.UserRate.Burst, "user-burst", c.Limits.UserRate.Burst, "messages a user may send at once over all its connections")
+	fs.Float64Var(&c.Limits.RoomRate.Rate, "room-rate", c.Limits.RoomRate.Rate, "messages a second a room accepts, 0 for no limit")
+	fs.IntVar(&c.Limits.RoomRate.Burst, "room-burst", c.Limits.RoomRate.Burst, "messages a room accepts at once")
+	fs.IntVar(&c.Limits.Mute.After, "mute-after", c.Limits.Mute.After, "rate limit hits within -mute-window that mute a connection, 0 to never mute")
+	fs.DurationVar(&c.Limits.Mute.Window, "mute-window", c.Limits.Mute.Window, "window rate limit hits are counted in")
+	fs.DurationVar(&c.Limits.Mute.Duration, "mute-duration", c.Limits.Mute.Duration, "how long a connection stays muted")
+
+	fs.DurationVar(&c.Retention.FederationDedupe, "federation-dedupe", c.Retention.FederationDedupe, "how long relayed message ids are remembered to drop duplicates")
+	fs.IntVar(&c.Retention.RaftSnapshots, "raft-snapshots", c.Retention.RaftSnapshots, "raft snapshots kept on disk")
+	fs.DurationVar(&c.Retention.StaleClients, "stale-clients", c.Retention.StaleClients, "how long clients of an unreachable replica, and the rooms they are in, are kept in raft mode")
+	fs.DurationVar(&c.Retention.DisconnectedClients, "disconnected-clients", c.Retention.DisconnectedClients, "how long clients are kept after their stream ends so they can resume, 0 to disconnect them right away")
+
+	fs.Var(&c.Features.Middleware, "middleware", "comma separated, ordered list of message middlewares")
+	fs.Var(&c.Features.BannedWords, "banned-words", "comma separated words the profanity middleware acts on")
+	fs.BoolVar(&c.Features.BlockProfanity, "block-profanity", c.Features.BlockProfanity, "reject messages with banned words instead of masking them")
+	fs.StringVar(&c.Features.Scripts, "scripts", c.Features.Scripts, "directory with *.star scripts to run, reloaded on change")
+	fs.StringVar(&c.Features.Webhooks, "webhooks", c.Features.Webhooks, "JSON file listing outgoing webhooks")
+	fs.StringVar(&c.Features.WebhooksDeadLetter, "webhooks-dead-letter", c.Features.WebhooksDeadLetter, "file failed webhook deliveries are appended to")
+	fs.StringVar(&c.Features.Federation, "federation", c.Features.Federation, "JSON file with this server's federation name and peers")
+	fs.StringVar(&c.Features.Integrations, "integrations", c.Features.Integrations, "JSON file listing integrations allowed to post to the incoming webhook")
+	fs.BoolVar(&c.Features.Metrics, "metrics", c.Features.Metrics, "serve prometheus metrics on /metrics")
+	fs.BoolVar(&c.Features.Gateway, "gateway", c.Features.Gateway, "serve the HTTP/JSON gateway on /api/v1")
+	fs.BoolVar(&c.Features.Websocket, "websocket", c.Features.Websocket, "serve websockets on /ws")
+	fs.Var(&c.Features.WSOrigins, "ws-origins", "comma separated origins allowed to open websockets, * for any, same origin only when empty")
+
+	fs.StringVar(&c.Log.Format, "log-format", c.Log.Format, "log output, text or json")
+	fs.StringVar(&c.Log.Level, "log-level", c.Log.Level, "log level, one of trace, debug, info, warn or error")
+	fs.Var(&c.Log.Levels, "log-levels", "comma separated subsystem=level overriding -log-level, e.g. raft=warn,grpc=debug")
+
+	fs.StringVar(&c.Trace.OTLP, "trace-otlp", c.Trace.OTLP, "host:port of an OTLP grpc collector to export traces to")
+	fs.StringVar(&c.Trace.File, "trace-file", c.Trace.File, "file to append traces to as JSON")
+
+	fs.StringVar(&c.Admin.Secret, "admin-secret", c.Admin.Secret, "bearer token of the Admin service, disabled when empty")
+
+	fs.DurationVar(&c.ShutdownTimeout, "shutdown-timeout", c.ShutdownTimeout, "how long streams get to send what is queued on SIGINT or SIGTERM")
+}
+
+// loadConfig reads the configuration file named by -config or
+// GOCHAT_CONFIG, then applies the environment and args on top. It also
+// reports whether -print-config was given.
+func loadConfig(name string, args []string) (*config, bool, error) {
+	// the first pass only looks for -config, every other flag is parsed
+	// again once the file was read
+	var path string
+	var print bool
+	fs := newConfigFlagSet(name, defaultConfig(), &path, &print)
+	fs.SetOutput(io.Discard)
+	path = os.Getenv(configEnvPrefix + "CONFIG")
+	if err := fs.Parse(args); err != nil && err != flag.ErrHelp {
+		return nil, false, err
+	}
+
+	c := defaultConfig()
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, false, err
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && err != io.EOF {
+			return nil, false, fmt.Errorf("invalid configuration file %s: %v", path, err)
+		}
+	}
+
+	fs = newConfigFlagSet(name, c, &path, &print)
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		env := configEnvPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if v, ok := os.LookupEnv(env); ok && err == nil {
+			if setErr := fs.Set(f.Name, v); setErr != nil {
+				err = fmt.Errorf("invalid value %q for %s: %v", v, env, setErr)
+			}
+		}
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, false, err
+	}
+	if fs.NArg() > 0 {
+		return nil, false, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	return c, print, nil
+}
+
+func newConfigFlagSet(name string, c *config, path *string, print *bool) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(path, "config", *path, "YAML configuration file, overridden by "+configEnvPrefix+"* environment variables and flags")
+	fs.BoolVar(print, "print-config", *print, "print the effective configuration and exit")
+	c.bind(fs)
+	return fs
+}
+
+// validate reports every invalid setting at once.
+func (c *config) validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+	checkAddr := func(setting, addr string) {
+		if addr == "" {
+			return
+		}
+		_, _, err := net.SplitHostPort(addr)
+		check(err == nil, "%s: invalid address %q", setting, addr)
+	}
+
+	check(c.Listen.GRPC != "", "listen.grpc is required")
+	checkAddr("listen.grpc", c.Listen.GRPC)
+	checkAddr("listen.http", c.Listen.HTTP)
+	checkAddr("listen.irc", c.Listen.IRC)
+
+	check((c.TLS.CertFile == "") == (c.TLS.KeyFile == ""), "tls.cert_file and tls.key_file go together")
+	check(c.TLS.CAFile == "" || c.TLS.CertFile != "", "tls.ca_file is only used with tls.cert_file")
+
+	raft := c.Storage.Raft
+	switch c.Storage.Backend {
+	case "memory":
+		check(raft == raftSettings{}, "storage.raft is only used with storage.backend raft")
+	case "raft":
+		check(raft.Addr != "", "storage.raft.addr is required with storage.backend raft")
+		checkAddr("storage.raft.addr", raft.Addr)
+		check(c.Node != "", "node must be set and stable with storage.backend raft")
+		check(raft.Bootstrap != (raft.Join != ""), "storage.raft needs either bootstrap or join")
+	default:
+		problems = append(problems, fmt.Sprintf("storage.backend: expected memory or raft, got %q", c.Storage.Backend))
+	}
+
+	check(c.Broker.Addr == "" || !c.Broker.Hub, "broker.addr and broker.hub are exclusive")
+	checkAddr("broker.addr", c.Broker.Addr)
+
+	check(c.Limits.MessageQueue > 0, "limits.message_queue must be positive")
+	check(c.Limits.EventQueue > 0, "limits.event_queue must be positive")
+	check(c.Limits.MaxMessageLength > 0, "limits.max_message_length must be positive")
+	checkRate := func(setting string, l rateLimit) {
+		check(l.Rate >= 0, "%s.rate must not be negative", setting)
+		check(l.Rate == 0 || l.Burst > 0, "%s.burst must be positive", setting)
+	}
+	checkRate("limits.session_rate", c.Limits.SessionRate)
+	checkRate("limits.user_rate", c.Limits.UserRate)
+	checkRate("limits.room_rate", c.Limits.RoomRate)
+	check(c.Limits.Mute.After >= 0, "limits.mute.after must not be negative")
+	check(c.Limits.Mute.After == 0 || c.Limits.Mute.Window > 0, "limits.mute.window must be positive")
+	check(c.Limits.Mute.After == 0 || c.Limits.Mute.Duration > 0, "limits.mute.duration must be positive")
+
+	check(c.Retention.FederationDedupe > 0, "retention.federation_dedupe must be positive")
+	check(c.Retention.RaftSnapshots > 0, "retention.raft_snapshots must be positive")
+	check(c.Retention.StaleClients > 0, "retention.stale_clients must be positive")
+	check(c.Retention.DisconnectedClients >= 0, "retention.disconnected_clients can not be negative")
+
+	for _, name := range c.Features.Middleware {
+		_, ok := middlewareFactories[name]
+		check(ok, "features.middleware: unknown middleware %q", name)
+	}
+
+	check(c.Log.Format == "text" || c.Log.Format == "json", "log.format: expected text or json, got %q", c.Log.Format)
+	_, err := parseLevel(c.Log.Level)
+	check(err == nil, "log.level: %v", err)
+	for name, level := range c.Log.Levels {
+		_, err := parseLevel(level)
+		check(err == nil, "log.levels.%s: %v", name, err)
+	}
+	checkHTTP := func(setting string, on bool) {
+		check(!on || c.Listen.HTTP != "", "%s needs listen.http", setting)
+	}
+	checkHTTP("features.metrics", c.Features.Metrics)
+	checkHTTP("features.gateway", c.Features.Gateway)
+	checkHTTP("features.websocket", c.Features.Websocket)
+	checkHTTP("features.integrations", c.Features.Integrations != "")
+	check(c.ShutdownTimeout > 0, "shutdown_timeout must be positive")
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// print writes the configuration as YAML, without secrets.
+func (c *config) print(w io.Writer) error {
+	redacted := *c
+	if redacted.Broker.Secret != "" {
+		redacted.Broker.Secret = "REDACTED"
+	}
+	if redacted.Admin.Secret != "" {
+		redacted.Admin.Secret = "REDACTED"
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&redacted); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// serverTLS returns the TLS configuration of the listeners, nil when TLS
+// is off.
+func (t tlsSettings) serverTLS() (*tls.Config, error) {
+	if t.CertFile == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load certificate: %v", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// dialOption returns the transport credentials other replicas and peers
+// are dialed with.
+func (t tlsSettings) dialOption() (grpc.DialOption, error) {
+	if t.CertFile == "" {
+		return grpc.WithTransportCredentials(insecure.NewCredentials()), nil
+	}
+	conf := &tls.Config{MinVersion: tls.VersionTLS12}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.RootCAs = x509.NewCertPool()
+		if !conf.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", t.CAFile)
+		}
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(conf)), nil
+}
+
+// selfTLS returns the TLS configuration this process dials itself with,
+// given the one of its listeners. Its certificate need not name the
+// address of the listener, so the certificate is trusted by itself and
+// checked against the first name it has.
+func selfTLS(server *tls.Config) (*tls.Config, error) {
+	leaf, err := x509.ParseCertificate(server.Certificates[0].Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("could not parse certificate: %v", err)
+	}
+	conf := &tls.Config{RootCAs: x509.NewCertPool(), MinVersion: tls.VersionTLS12}
+	conf.RootCAs.AddCert(leaf)
+	switch {
+	case len(leaf.DNSNames) > 0:
+		conf.ServerName = leaf.DNSNames[0]
+	case len(leaf.IPAddresses) > 0:
+		conf.ServerName = leaf.IPAddresses[0].String()
+	default:
+		return nil, fmt.Errorf("certificate has no DNS or IP subject alternative name")
+	}
+	return conf, nil
+}
+
+// stringList is a comma separated flag and a YAML list.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = nil
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// levelsValue is a comma separated list of subsystem=level as a flag and a
+// mapping as YAML.
+type levelsValue map[string]string
+
+func (v *levelsValue) String() string {
+	var pairs []string
+	for name, level := range *v {
+		pairs = append(pairs, name+"="+level)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (v *levelsValue) Set(s string) error {
+	levels := levelsValue{}
+	for _, kv := range strings.Split(s, ",") {
+		if kv = strings.TrimSpace(kv); kv == "" {
+			continue
+		}
+		i := strings.Index(kv, "=")
+		if i <= 0 {
+			return fmt.Errorf("invalid subsystem level %q, expected name=level", kv)
+		}
+		levels[kv[:i]] = kv[i+1:]
+	}
+	*v = levels
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+type serverEventKind int
+
+const (
+	clientConnected serverEventKind = iota
+	clientDisconnected
+	clientRenamed
+	profileUpdated
+	messageSent
+	roomJoined
+	roomLeft
+	roomTopicChanged
+)
+
+func (k serverEventKind) String() string {
+	switch k {
+	case clientConnected:
+		return "client.connected"
+	case clientDisconnected:
+		return "client.disconnected"
+	case clientRenamed:
+		return "client.renamed"
+	case profileUpdated:
+		return "client.profile"
+	case messageSent:
+		return "message.sent"
+	case roomJoined:
+		return "room.joined"
+	case roomLeft:
+		return "room.left"
+	case roomTopicChanged:
+		return "room.topic"
+	}
+	return "unknown"
+}
+
+// serverEvent is passed to listeners after something happened. client is
+// a copy taken at the time of the event, the client that sent the message
+// or joined, left or changed the room. oldName is set when it was renamed.
+type serverEvent struct {
+	kind    serverEventKind
+	client  client
+	message *chatMessage
+	room    string
+	topic   string
+	oldName string
+}
+
+type eventListener func(ev serverEvent)
+
+// addListener must be called before the server starts serving.
+func (s *server) addListener(l eventListener) {
+	s.listeners = append(s.listeners, l)
+}
+
+// emit calls listeners synchronously, it must not be called with
+// clientsMu held.
+func (s *server) emit(ev serverEvent) {
+	for _, l := range s.listeners {
+		l(ev)
+	}
+}
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	FederationQueueSize   = 1000
+	FederationMaxAttempts = 8
+	FederationTimeout     = 10 * time.Second
+	FederationBackoff     = time.Second
+	FederationMaxBackoff  = time.Minute
+	// presence events queued for a peer before its stream is reset
+	FederationPresenceQueue = 1000
+)
+
+// seen relay ids are pruned once there are this many
+const seenPruneSize = 10000
+
+const peerMetadataKey = "gochat-peer"
+
+var peerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*$`)
+
+// federatedNamespace derives stable local ids for users of peer servers.
+var federatedNamespace = uuid.MustParse("6f1c7b53-3c1e-4a53-9a0e-6d0fb7c5d1e2")
+
+type peerConfig struct {
+	Name string `json:"name"`
+	Addr string `json:"addr"`
+	// Secret is shared with the peer, both sides use it to authenticate.
+	Secret string `json:"secret"`
+}
+
+// federationConfig is the -federation file.
+type federationConfig struct {
+	// Name of this server, the part after @ in its users' names on peers.
+	Name  string       `json:"name"`
+	Peers []peerConfig `json:"peers"`
+}
+
+func loadFederationConfig(path string) (federationConfig, error) {
+	var cfg federationConfig
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("could not parse %s: %v", path, err)
+	}
+	if !peerNamePattern.MatchString(cfg.Name) {
+		return cfg, fmt.Errorf("invalid server name %q", cfg.Name)
+	}
+	seen := map[string]bool{cfg.Name: true}
+	for i, p := range cfg.Peers {
+		if !peerNamePattern.MatchString(p.Name) || seen[p.Name] {
+			return cfg, fmt.Errorf("peer %d: invalid or duplicate name %q", i, p.Name)
+		}
+		if p.Addr == "" || len(p.Secret) < 16 {
+			return cfg, fmt.Errorf("peer %s: addr is required and secret must be at least 16 characters", p.Name)
+		}
+		seen[p.Name] = true
+	}
+	return cfg, nil
+}
+
+// federation connects this server to its peers. Users of a peer are added
+// to the clients as "name@peer" while the peer's presence stream is up,
+// direct messages to them are relayed with retries. Rooms stay local.
+type federation struct {
+	pb.UnimplementedFederationServer
+	s     *server
+	log   hclog.Logger
+	name  string
+	peers map[string]*federationPeer
+
+	subscribersMu sync.Mutex
+	subscribers   map[*presenceSubscriber]struct{}
+
+	seenMu sync.Mutex
+	seen   map[string]time.Time
+	// how long ids stay in seen
+	dedupeWindow time.Duration
+}
+
+type federationPeer struct {
+	peerConfig
+	f     *federation
+	log   hclog.Logger
+	api   pb.FederationClient
+	queue chan *pb.FederatedMessage
+}
+
+type presenceSubscriber struct {
+	events chan *pb.PresenceEvent
+	cancel context.CancelFunc
+}
+
+func newFederation(s *server, cfg federationConfig) (*federation, error) {
+	f := &federation{
+		s:            s,
+		log:          s.logs.named("federation"),
+		name:         cfg.Name,
+		peers:        make(map[string]*federationPeer),
+		subscribers:  make(map[*presenceSubscriber]struct{}),
+		seen:         make(map[string]time.Time),
+		dedupeWindow: s.config().Retention.FederationDedupe,
+	}
+	for _, pc := range cfg.Peers {
+		conn, err := grpc.Dial(pc.Addr, s.dialCreds)
+		if err != nil {
+			return nil, fmt.Errorf("could not dial peer %s: %v", pc.Name, err)
+		}
+		f.peers[pc.Name] = &federationPeer{
+			peerConfig: pc,
+			f:          f,
+			log:        f.log.With("peer", pc.Name),
+			api:        pb.NewFederationClient(conn),
+			queue:      make(chan *pb.FederatedMessage, FederationQueueSize),
+		}
+	}
+	return f, nil
+}
+
+func (f *federation) run(ctx context.Context) {
+	for _, p := range f.peers {
+		go p.followPresence(ctx)
+		go p.relayWorker(ctx)
+	}
+}
+
+// remoteID is the local id of a user of a peer server.
+func remoteID(peer, id string) uuid.UUID {
+	return uuid.NewSHA1(federatedNamespace, []byte(peer+"/"+id))
+}
+
+// authenticate checks the calling peer's name and secret.
+func (f *federation) authenticate(ctx context.Context) (*federationPeer, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var name string
+	if v := md.Get(peerMetadataKey); len(v) > 0 {
+		name = v[0]
+	}
+	p, ok := f.peers[name]
+	if !ok || !hasBearerToken(ctx, p.Secret) {
+		return nil, status.Errorf(codes.Unauthenticated, "unknown peer or invalid secret")
+	}
+	return p, nil
+}
+
+func (p *federationPeer) outgoingContext(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, peerMetadataKey, p.f.name, "authorization", "Bearer "+p.Secret)
+}
+
+// Relay delivers a message from a user of the calling peer.
+func (f *federation) Relay(ctx context.Context, in *pb.FederatedMessage) (*pb.RelayResponse, error) {
+	p, err := f.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if in.GetId() == "" || in.GetSender().GetId() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "message id and sender are required")
+	}
+	if f.duplicate(p.Name + "/" + in.GetId()) {
+		return &pb.RelayResponse{}, nil
+	}
+
+	m := chatMessage{
+		sender:    remoteID(p.Name, in.GetSender().GetId()).String(),
+		recipient: in.GetRecipientId(),
+		text:      in.GetText(),
+	}
+	for k, v := range in.GetMetadata() {
+		setMetadata(&m, k, v)
+	}
+	setMetadata(&m, "federated_from", p.Name)
+
+	f.s.clientsMu.Lock()
+	recipient, err := f.s.findLocalClient(m.recipient)
+	f.s.clientsMu.Unlock()
+	if err != nil {
+		f.forget(p.Name + "/" + in.GetId())
+		return nil, err
+	}
+	if err := f.s.send(ctx, m); err != nil {
+		f.forget(p.Name + "/" + in.GetId())
+		return nil, err
+	}
+	requestLogger(ctx, f.log).Debug("relayed message", "peer", p.Name, "sender", in.GetSender().GetName(), "recipient", recipient.name)
+	return &pb.RelayResponse{}, nil
+}
+
+// duplicate records id and reports whether it was seen recently.
+func (f *federation) duplicate(id string) bool {
+	f.seenMu.Lock()
+	defer f.seenMu.Unlock()
+	now := time.Now()
+	if at, ok := f.seen[id]; ok && now.Sub(at) < f.dedupeWindow {
+		return true
+	}
+	if len(f.seen) >= seenPruneSize {
+		for k, at := range f.seen {
+			if now.Sub(at) >= f.dedupeWindow {
+				delete(f.seen, k)
+			}
+		}
+	}
+	f.seen[id] = now
+	return false
+}
+
+// forget drops an id after a failed delivery so a retry is not dropped.
+func (f *federation) forget(id string) {
+	f.seenMu.Lock()
+	defer f.seenMu.Unlock()
+	delete(f.seen, id)
+}
+
+// Presence streams the local users to the calling peer.
+func (f *federation) Presence(in *pb.PresenceRequest, stream pb.Federation_PresenceServer) error {
+	p, err := f.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	sub := &presenceSubscriber{events: make(chan *pb.PresenceEvent, FederationPresenceQueue), cancel: cancel}
+	// subscribe before taking the snapshot so no change is missed, peers
+	// handle the odd duplicate
+	f.subscribersMu.Lock()
+	f.subscribers[sub] = struct{}{}
+	f.subscribersMu.Unlock()
+	defer func() {
+		f.subscribersMu.Lock()
+		delete(f.subscribers, sub)
+		f.subscribersMu.Unlock()
+	}()
+
+	f.s.clientsMu.Lock()
+	var snapshot []*pb.PresenceEvent
+	for _, c := range f.s.clients {
+		if c.peer == nil {
+			snapshot = append(snapshot, presenceEvent(c, pb.PresenceEvent_ONLINE))
+		}
+	}
+	f.s.clientsMu.Unlock()
+
+	requestLogger(stream.Context(), f.log).Info("peer subscribed to presence", "peer", p.Name)
+	if err := stream.SendHeader(metadata.Pairs(subscribedHeader, "true")); err != nil {
+		return err
+	}
+	for _, ev := range snapshot {
+		if err := stream.Send(ev); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			if stream.Context().Err() == nil {
+				return status.Errorf(codes.Aborted, "presence queue full")
+			}
+			return nil
+		case <-f.s.goingAway:
+			return errGoingAway()
+		case ev := <-sub.events:
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func presenceEvent(c *client, st pb.PresenceEvent_Status) *pb.PresenceEvent {
+	return &pb.PresenceEvent{Client: connectedClient(c), Status: st}
+}
+
+// handleEvent is an eventListener passing local presence on to peers.
+func (f *federation) handleEvent(ev serverEvent) {
+	var st pb.PresenceEvent_Status
+	switch ev.kind {
+	case clientConnected, clientRenamed, profileUpdated:
+		// an ONLINE event for a known user updates it on the peer
+		st = pb.PresenceEvent_ONLINE
+	case clientDisconnected:
+		st = pb.PresenceEvent_OFFLINE
+	default:
+		return
+	}
+	if ev.client.peer != nil {
+		return
+	}
+	pe := presenceEvent(&ev.client, st)
+	f.subscribersMu.Lock()
+	defer f.subscribersMu.Unlock()
+	for sub := range f.subscribers {
+		select {
+		case sub.events <- pe:
+		default:
+			// the peer resubscribes and gets a fresh snapshot
+			sub.cancel()
+		}
+	}
+}
+
+// followPresence keeps the users of the peer in sync for as long as ctx
+// lives, reconnecting with backoff.
+func (p *federationPeer) followPresence(ctx context.Context) {
+	backoff := FederationBackoff
+	for {
+		subscribed, err := p.presenceSession(ctx)
+		p.dropUsers()
+		if ctx.Err() != nil {
+			return
+		}
+		if subscribed {
+			backoff = FederationBackoff
+		}
+		p.log.Warn("presence lost, retrying", "error", err, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > FederationMaxBackoff {
+			backoff = FederationMaxBackoff
+		}
+	}
+}
+
+func (p *federationPeer) presenceSession(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := p.api.Presence(p.outgoingContext(ctx), &pb.PresenceRequest{})
+	if err != nil {
+		return false, err
+	}
+	if err := waitSubscribed(stream, &pb.PresenceEvent{}); err != nil {
+		return false, err
+	}
+	p.log.Info("following presence")
+	for {
+		ev, err := stream.Recv()
+		if err == io.EOF {
+			return true, fmt.Errorf("stream closed")
+		}
+		if err != nil {
+			return true, err
+		}
+		p.applyPresence(ev)
+	}
+}
+
+func (p *federationPeer) applyPresence(ev *pb.PresenceEvent) {
+	remote := ev.GetClient()
+	if remote.GetId() == "" {
+		return
+	}
+	id := remoteID(p.Name, remote.GetId())
+	s := p.f.s
+
+	if ev.GetStatus() == pb.PresenceEvent_OFFLINE {
+		s.clientsMu.Lock()
+		c, ok := s.clients[id]
+		s.clientsMu.Unlock()
+		if ok {
+			s.disconnect(c)
+		}
+		return
+	}
+
+	// peers are trusted with their users, not with what they say about
+	// them, statuses are expired by the peer so now is not checked
+	if err := validateName("name", remote.GetName()); err != nil {
+		p.log.Warn("ignoring presence of invalid user", "id", remote.GetId(), "error", err)
+		return
+	}
+	if err := validateProfile(remote.GetProfile(), profileFields, time.Time{}); err != nil {
+		p.log.Warn("ignoring presence of invalid user", "id", remote.GetId(), "error", err)
+		return
+	}
+	name := remote.GetName() + "@" + p.Name
+	s.clientsMu.Lock()
+	if c, ok := s.clients[id]; ok {
+		old := c.name
+		c.name = name
+		var events []serverEvent
+		if old != name {
+			s.broadcastRename(c, old)
+			events = append(events, serverEvent{kind: clientRenamed, client: *c, oldName: old})
+		}
+		if !proto.Equal(c.profile, remote.GetProfile()) {
+			c.profile = remote.GetProfile()
+			s.broadcastPresence(c, pb.PresenceEvent_UPDATED)
+			events = append(events, serverEvent{kind: profileUpdated, client: *c})
+		}
+		s.clientsMu.Unlock()
+		for _, ev := range events {
+			s.emit(ev)
+		}
+		return
+	}
+	c := &client{clientId: id, name: name, peer: p, remoteId: remote.GetId(), profile: remote.GetProfile()}
+	s.clients[id] = c
+	s.broadcastPresence(c, pb.PresenceEvent_ONLINE)
+	joined := *c
+	s.clientsMu.Unlock()
+	s.emit(serverEvent{kind: clientConnected, client: joined})
+}
+
+// dropUsers disconnects all users of the peer once its presence is lost.
+func (p *federationPeer) dropUsers() {
+	s := p.f.s
+	s.clientsMu.Lock()
+	var gone []*client
+	for _, c := range s.clients {
+		if c.peer == p {
+			gone = append(gone, c)
+		}
+	}
+	s.clientsMu.Unlock()
+	for _, c := range gone {
+		s.disconnect(c)
+	}
+}
+
+// relay queues m for the peer of recipient. It never blocks and must be
+// called with clientsMu held.
+func (p *federationPeer) relay(recipient *client, m chatMessage) {
+	sender := &pb.ConnectedClientsResponse_ConnectedClient{Id: m.sender, Name: p.f.name}
+	if id, err := uuid.Parse(m.sender); err == nil {
+		if c, ok := p.f.s.clients[id]; ok {
+			sender.Name = c.name
+		}
+	}
+	fm := &pb.FederatedMessage{
+		Id:          uuid.NewString(),
+		Sender:      sender,
+		RecipientId: recipient.remoteId,
+		Text:        m.text,
+		Metadata:    m.metadata,
+	}
+	select {
+	case p.queue <- fm:
+	default:
+		p.log.Warn("relay queue full, dropping message")
+		messagesDropped.WithLabelValues("federation").Inc()
+	}
+}
+
+func (p *federationPeer) relayWorker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case fm := <-p.queue:
+			p.deliver(ctx, fm)
+		}
+	}
+}
+
+// deliver retries transient failures, the message id stays the same so
+// the peer drops duplicates of attempts that did arrive.
+func (p *federationPeer) deliver(ctx context.Context, fm *pb.FederatedMessage) {
+	backoff := FederationBackoff
+	for attempt := 1; attempt <= FederationMaxAttempts; attempt++ {
+		callCtx, cancel := context.WithTimeout(p.outgoingContext(ctx), FederationTimeout)
+		_, err := p.api.Relay(callCtx, fm)
+		cancel()
+		if err == nil {
+			return
+		}
+		switch status.Code(err) {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted, codes.Internal:
+		default:
+			p.log.Error("peer rejected message", "message_id", fm.GetId(), "error", err)
+			return
+		}
+		if attempt == FederationMaxAttempts {
+			break
+		}
+		p.log.Warn("relay failed, retrying", "message_id", fm.GetId(), "attempt", attempt, "error", err, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > FederationMaxBackoff {
+			backoff = FederationMaxBackoff
+		}
+	}
+	p.log.Error("gave up relaying message", "message_id", fm.GetId())
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const GatewayMaxBody = 64 << 10
+
+var (
+	gatewayMarshal   = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+	gatewayUnmarshal = protojson.UnmarshalOptions{}
+	// http headers passed on to the grpc server as metadata
+	gatewayForwardedHeaders = []string{"Authorization", "X-Request-Id"}
+)
+
+// gateway exposes the ChatServer service as HTTP/JSON. Requests are
+// forwarded over a grpc connection to this same server, so they go
+// through exactly the same handlers and interceptors as grpc clients.
+//
+//	GET  /api/v1/clients                          GetConnectedClients
+//	POST /api/v1/connect                          Connect
+//	POST /api/v1/disconnect                       Disconnect
+//	GET  /api/v1/users/resolve?name=<name>        ResolveUser
+//	POST /api/v1/users/name                       ChangeName
+//	GET  /api/v1/users/profile?client_id=<id>     GetProfile
+//	POST /api/v1/users/profile                    UpdateProfile
+//	POST /api/v1/messages                         Message
+//	GET  /api/v1/messages/stream?client_id=<id>   ReceiveMessages
+//	GET  /api/v1/events/stream?client_id=<id>     ReceiveEvents
+//	GET  /api/v1/commands                         ListCommands
+//
+// Streams are sent as Server-Sent Events when the request accepts
+// text/event-stream, as newline delimited JSON otherwise.
+type gateway struct {
+	api pb.ChatServerClient
+}
+
+func newGateway(conn *grpc.ClientConn) *gateway {
+	return &gateway{api: pb.NewChatServerClient(conn)}
+}
+
+func (g *gateway) register(mux *http.ServeMux) {
+	mux.HandleFunc("/api/v1/clients", g.handleClients)
+	mux.HandleFunc("/api/v1/connect", g.handleConnect)
+	mux.HandleFunc("/api/v1/disconnect", g.handleDisconnect)
+	mux.HandleFunc("/api/v1/users/resolve", g.handleResolveUser)
+	mux.HandleFunc("/api/v1/users/name", g.handleChangeName)
+	mux.HandleFunc("/api/v1/users/profile", g.handleProfile)
+	mux.HandleFunc("/api/v1/messages", g.handleMessage)
+	mux.HandleFunc("/api/v1/messages/stream", g.handleReceiveMessages)
+	mux.HandleFunc("/api/v1/events/stream", g.handleReceiveEvents)
+	mux.HandleFunc("/api/v1/commands", g.handleCommands)
+}
+
+// gatewayContext passes the forwarded headers of r and the address it
+// came from on to the grpc server.
+func gatewayContext(r *http.Request) context.Context {
+	pairs := []string{remoteAddrHeader, r.RemoteAddr}
+	for _, h := range gatewayForwardedHeaders {
+		if v := r.Header.Get(h); v != "" {
+			pairs = append(pairs, strings.ToLower(h), v)
+		}
+	}
+	return metadata.AppendToOutgoingContext(r.Context(), pairs...)
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeHTTPError(w, http.StatusMethodNotAllowed, "method not allowed")
+	return false
+}
+
+func readProto(w http.ResponseWriter, r *http.Request, m proto.Message) bool {
+	b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, GatewayMaxBody))
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, fmt.Sprintf("could not read body: %v", err))
+		return false
+	}
+	if err := gatewayUnmarshal.Unmarshal(b, m); err != nil {
+		writeHTTPError(w, http.StatusBadRequest, fmt.Sprintf("invalid body: %v", err))
+		return false
+	}
+	return true
+}
+
+func writeProto(w http.ResponseWriter, m proto.Message) {
+	b, err := gatewayMarshal.Marshal(m)
+	if err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func (g *gateway) handleClients(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	resp, err := g.api.GetConnectedClients(gatewayContext(r), &pb.ConnectedClientsRequest{})
+	if err != nil {
+		writeStatusError(w, err)
+		return
+	}
+	writeProto(w, resp)
+}
+
+func (g *gateway) handleConnect(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	var req pb.ConnectRequest
+	if !readProto(w, r, &req) {
+		return
+	}
+	resp, err := g.api.Connect(gatewayContext(r), &req)
+	if err != nil {
+		writeStatusError(w, err)
+		return
+	}
+	writeProto(w, resp)
+}
+
+func (g *gateway) handleDisconnect(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	var req pb.DisconnectRequest
+	if !readProto(w, r, &req) {
+		return
+	}
+	resp, err := g.api.Disconnect(gatewayContext(r), &req)
+	if err != nil {
+		writeStatusError(w, err)
+		return
+	}
+	writeProto(w, resp)
+}
+
+func (g *gateway) handleResolveUser(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	resp, err := g.api.ResolveUser(gatewayContext(r), &pb.ResolveUserRequest{Name: r.URL.Query().Get("name")})
+	if err != nil {
+		writeStatusError(w, err)
+		return
+	}
+	writeProto(w, resp)
+}
+
+func (g *gateway) handleCommands(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	resp, err := g.api.ListCommands(gatewayContext(r), &pb.ListCommandsRequest{})
+	if err != nil {
+		writeStatusError(w, err)
+		return
+	}
+	writeProto(w, resp)
+}
+
+func (g *gateway) handleChangeName(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	var req pb.ChangeNameRequest
+	if !readProto(w, r, &req) {
+		return
+	}
+	resp, err := g.api.ChangeName(gatewayContext(r), &req)
+	if err != nil {
+		writeStatusError(w, err)
+		return
+	}
+	writeProto(w, resp)
+}
+
+// handleProfile gets the profile on GET and updates it on POST.
+func (g *gateway) handleProfile(w http.ResponseWriter, r *http.Request) {
+	var resp proto.Message
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		resp, err = g.api.GetProfile(gatewayContext(r), &pb.GetProfileRequest{ClientId: r.URL.Query().Get("client_id")})
+	case http.MethodPost:
+		var req pb.UpdateProfileRequest
+		if !readProto(w, r, &req) {
+			return
+		}
+		resp, err = g.api.UpdateProfile(gatewayContext(r), &req)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeHTTPError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if err != nil {
+		writeStatusError(w, err)
+		return
+	}
+	writeProto(w, resp)
+}
+
+func (g *gateway) handleMessage(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	var req pb.ChatMessage
+	if !readProto(w, r, &req) {
+		return
+	}
+	var trailer metadata.MD
+	resp, err := g.api.Message(gatewayContext(r), &req, grpc.Trailer(&trailer))
+	if err != nil {
+		if v := trailer.Get(retryAfterHeader); len(v) > 0 {
+			w.Header().Set("Retry-After", v[0])
+		}
+		writeStatusError(w, err)
+		return
+	}
+	writeProto(w, resp)
+}
+
+func (g *gateway) handleReceiveMessages(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	stream, err := g.api.ReceiveMessages(gatewayContext(r), &pb.ReceiveRequest{ClientId: r.URL.Query().Get("client_id")})
+	if err != nil {
+		writeStatusError(w, err)
+		return
+	}
+	if err := waitSubscribed(stream, &pb.ChatMessage{}); err != nil {
+		writeStatusError(w, err)
+		return
+	}
+	proxyStream(w, r, func() (proto.Message, string, error) {
+		m, err := stream.Recv()
+		return m, "message", err
+	})
+}
+
+func (g *gateway) handleReceiveEvents(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	stream, err := g.api.ReceiveEvents(gatewayContext(r), &pb.ReceiveRequest{ClientId: r.URL.Query().Get("client_id")})
+	if err != nil {
+		writeStatusError(w, err)
+		return
+	}
+	if err := waitSubscribed(stream, &pb.Event{}); err != nil {
+		writeStatusError(w, err)
+		return
+	}
+	proxyStream(w, r, func() (proto.Message, string, error) {
+		ev, err := stream.Recv()
+		if err != nil {
+			return nil, "", err
+		}
+		name := "message"
+		switch {
+		case ev.GetPresence() != nil:
+			name = "presence"
+		case ev.GetRenamed() != nil:
+			name = "renamed"
+		case ev.GetGoingAway() != nil:
+			name = "going_away"
+		}
+		return ev, name, nil
+	})
+}
+
+// waitSubscribed waits until the server accepted a receive stream. m is
+// only used to receive the status of a stream that failed.
+func waitSubscribed(stream grpc.ClientStream, m proto.Message) error {
+	md, err := stream.Header()
+	if err != nil {
+		return err
+	}
+	if len(md.Get(subscribedHeader)) == 0 {
+		if err := stream.RecvMsg(m); err != nil {
+			return err
+		}
+		return status.Errorf(codes.Internal, "stream was not accepted")
+	}
+	return nil
+}
+
+// proxyStream copies a subscribed stream to w until either side goes
+// away. recv returns the next message and its SSE event name.
+func proxyStream(w http.ResponseWriter, r *http.Request, recv func() (proto.Message, string, error)) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeHTTPError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+	sse := strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+	if sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		m, name, err := recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			if r.Context().Err() == nil {
+				writeStreamError(w, sse, err)
+				flusher.Flush()
+			}
+			return
+		}
+		b, err := gatewayMarshal.Marshal(m)
+		if err != nil {
+			writeStreamError(w, sse, err)
+			flusher.Flush()
+			return
+		}
+		if sse {
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, b)
+		} else {
+			w.Write(append(b, '\n'))
+		}
+		flusher.Flush()
+	}
+}
+
+func writeStreamError(w io.Writer, sse bool, err error) {
+	b, _ := json.Marshal(map[string]string{"error": err.Error()})
+	if sse {
+		fmt.Fprintf(w, "event: error\ndata: %s\n\n", b)
+		return
+	}
+	w.Write(append(b, '\n'))
+}
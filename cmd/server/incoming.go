@@ -0,0 +1,195 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const IncomingWebhookMaxBody = 64 << 10
+
+// integration is one entry of the -integrations file, an external system
+// allowed to post messages with its token.
+type integration struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+	// DisplayName is used when a request does not set display_name.
+	DisplayName string `json:"display_name"`
+}
+
+func loadIntegrations(path string) ([]integration, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var integrations []integration
+	if err := json.Unmarshal(b, &integrations); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", path, err)
+	}
+	for i, in := range integrations {
+		if in.Name == "" || len(in.Token) < 16 {
+			return nil, fmt.Errorf("integration %d: name is required and token must be at least 16 characters", i)
+		}
+	}
+	return integrations, nil
+}
+
+type incomingMessage struct {
+	Text string `json:"text"`
+	Room string `json:"room"`
+	// Recipient is a user name or id.
+	Recipient   string `json:"recipient"`
+	DisplayName string `json:"display_name"`
+}
+
+// incomingWebhookHandler accepts POST requests with a bearer token and
+// sends the JSON body as a message from the server, through the same
+// pipeline as Message. Texts are checked like those of Message and every
+// integration is rate limited like a user.
+type incomingWebhookHandler struct {
+	s            *server
+	integrations []integration
+}
+
+func (h *incomingWebhookHandler) authenticate(r *http.Request) (integration, bool) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return integration{}, false
+	}
+	token := strings.TrimPrefix(auth, "Bearer ")
+	if token == "" {
+		return integration{}, false
+	}
+	for _, in := range h.integrations {
+		if subtle.ConstantTimeCompare([]byte(in.Token), []byte(token)) == 1 {
+			return in, true
+		}
+	}
+	return integration{}, false
+}
+
+func (h *incomingWebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeHTTPError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	in, ok := h.authenticate(r)
+	if !ok {
+		writeHTTPError(w, http.StatusUnauthorized, "missing or invalid token")
+		return
+	}
+
+	var body incomingMessage
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, IncomingWebhookMaxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&body); err != nil {
+		writeHTTPError(w, http.StatusBadRequest, fmt.Sprintf("invalid body: %v", err))
+		return
+	}
+	if (body.Room == "") == (body.Recipient == "") {
+		writeHTTPError(w, http.StatusBadRequest, "exactly one of room and recipient must be set")
+		return
+	}
+	if err := validateMessageText(body.Text, h.s.config().Limits.MaxMessageLength); err != nil {
+		writeStatusError(w, err)
+		return
+	}
+
+	displayName := body.DisplayName
+	if displayName == "" {
+		displayName = in.DisplayName
+	}
+	if displayName == "" {
+		displayName = in.Name
+	}
+	m := chatMessage{sender: systemSender.String(), text: body.Text}
+	setMetadata(&m, "integration", in.Name)
+	setMetadata(&m, "display_name", displayName)
+
+	if body.Room != "" {
+		room, err := roomName(body.Room)
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		m.room = room
+	} else {
+		recipient, err := h.s.findClient(body.Recipient)
+		if err != nil {
+			writeStatusError(w, err)
+			return
+		}
+		m.recipient = recipient.clientId.String()
+	}
+	if wait, err := h.s.limiter.allowIntegration(in.Name, m.room); err != nil {
+		w.Header().Set("Retry-After", strconv.Itoa(int(roundUp(wait)/time.Second)))
+		writeStatusError(w, err)
+		return
+	}
+
+	if err := h.s.send(r.Context(), m); err != nil {
+		writeStatusError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findClient looks a client up by id, falling back to its name.
+func (s *server) findClient(idOrName string) (*client, error) {
+	if id, err := uuid.Parse(idOrName); err == nil {
+		s.clientsMu.Lock()
+		defer s.clientsMu.Unlock()
+		return getClientById(id, s.clients)
+	}
+	return s.getClientByName(idOrName)
+}
+
+func writeHTTPError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// writeStatusError maps a grpc status error onto the closest http status.
+func writeStatusError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	writeHTTPError(w, httpStatusFromCode(st.Code()), st.Message())
+}
+
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.Canceled:
+		return 499
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,729 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	IRCServerName   = "go-chat"
+	IRCMaxLine      = 8192
+	IRCQueueSize    = 256
+	IRCPingInterval = 2 * time.Minute
+	IRCIdleTimeout  = 5 * time.Minute
+	IRCWriteTimeout = 10 * time.Second
+)
+
+// ircServer lets standard IRC clients use the chat. NICK/USER connect,
+// PRIVMSG goes through Message like any other client, channels are rooms.
+// It is registered as an eventListener to tell IRC users about others
+// joining, leaving and changing the topic of their channels.
+type ircServer struct {
+	s   *server
+	log hclog.Logger
+
+	mu    sync.Mutex
+	conns map[uuid.UUID]*ircConn
+}
+
+func newIRCServer(s *server) *ircServer {
+	return &ircServer{s: s, log: s.logs.named("irc"), conns: make(map[uuid.UUID]*ircConn)}
+}
+
+func (i *ircServer) serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		c := &ircConn{i: i, conn: conn, out: make(chan string, IRCQueueSize)}
+		go c.serve()
+	}
+}
+
+// handleEvent is an eventListener, it never blocks.
+func (i *ircServer) handleEvent(ev serverEvent) {
+	var line string
+	prefix := ircPrefix(ev.client.name)
+	switch ev.kind {
+	case roomJoined:
+		line = fmt.Sprintf(":%s JOIN %s", prefix, ev.room)
+	case roomLeft:
+		line = fmt.Sprintf(":%s PART %s", prefix, ev.room)
+	case roomTopicChanged:
+		line = fmt.Sprintf(":%s TOPIC %s :%s", prefix, ev.room, ev.topic)
+	default:
+		return
+	}
+	r, err := i.s.getRoom(ev.room)
+	if err != nil {
+		// the last member left
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for id := range r.members {
+		// IRC users see their own changes in the reply to their command
+		if id == ev.client.clientId {
+			continue
+		}
+		if c, ok := i.conns[id]; ok {
+			c.trySend(line)
+		}
+	}
+}
+
+// findNick looks a client up by name, IRC users only see names as
+// ircNick shows them.
+func (i *ircServer) findNick(nick string) (*client, error) {
+	c, err := i.s.getClientByName(nick)
+	if status.Code(err) != codes.NotFound {
+		return c, err
+	}
+	i.s.clientsMu.Lock()
+	defer i.s.clientsMu.Unlock()
+	for _, c := range i.s.clients {
+		if ircNick(c.name) == nick {
+			return c, nil
+		}
+	}
+	return nil, err
+}
+
+type ircMessage struct {
+	command string
+	params  []string
+}
+
+// parseIRCLine parses "[@tags] [:prefix] COMMAND params [:trailing]",
+// tags and prefix are ignored.
+func parseIRCLine(line string) ircMessage {
+	line = strings.TrimRight(line, "\r\n")
+	if strings.HasPrefix(line, "@") {
+		_, line, _ = strings.Cut(line, " ")
+	}
+	if strings.HasPrefix(line, ":") {
+		_, line, _ = strings.Cut(line, " ")
+	}
+	var m ircMessage
+	line = strings.TrimLeft(line, " ")
+	for line != "" {
+		if strings.HasPrefix(line, ":") && m.command != "" {
+			m.params = append(m.params, line[1:])
+			break
+		}
+		var word string
+		word, line, _ = strings.Cut(line, " ")
+		line = strings.TrimLeft(line, " ")
+		if m.command == "" {
+			m.command = strings.ToUpper(word)
+		} else {
+			m.params = append(m.params, word)
+		}
+	}
+	return m
+}
+
+// ircNick makes a chat name usable as an IRC nick.
+func ircNick(name string) string {
+	if name == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" ,*?!@:#\r\n\t", r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// ircPrefix is the source of lines sent on behalf of a user, their
+// address is not shared.
+func ircPrefix(name string) string {
+	nick := ircNick(name)
+	return fmt.Sprintf("%s!%s@%s", nick, nick, IRCServerName)
+}
+
+type ircConn struct {
+	i    *ircServer
+	conn net.Conn
+
+	out    chan string
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// only used by the read loop until registered
+	nick, user string
+	client     *client
+}
+
+func (c *ircConn) serve() {
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	defer c.cancel()
+	written := make(chan struct{})
+	go c.writer(written)
+	defer func() {
+		c.cancel()
+		<-written
+	}()
+
+	c.readLoop()
+
+	if c.client != nil {
+		c.i.mu.Lock()
+		delete(c.i.conns, c.client.clientId)
+		c.i.mu.Unlock()
+		c.i.s.disconnect(c.client)
+	}
+}
+
+func (c *ircConn) readLoop() {
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 0, 512), IRCMaxLine)
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(IRCIdleTimeout))
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil && !errors.Is(err, net.ErrClosed) {
+				c.i.log.Warn("read failed", "remote_addr", c.conn.RemoteAddr().String(), "error", err)
+			}
+			return
+		}
+		m := parseIRCLine(scanner.Text())
+		if m.command == "" {
+			continue
+		}
+		if !c.handle(m) {
+			return
+		}
+	}
+}
+
+// writer is the only one writing to the connection. Once the connection
+// is done it flushes what is queued and closes it.
+func (c *ircConn) writer(done chan struct{}) {
+	defer close(done)
+	defer c.conn.Close()
+	ping := time.NewTicker(IRCPingInterval)
+	defer ping.Stop()
+	w := bufio.NewWriter(c.conn)
+	write := func(line string) bool {
+		c.conn.SetWriteDeadline(time.Now().Add(IRCWriteTimeout))
+		if _, err := w.WriteString(line + "\r\n"); err != nil {
+			return false
+		}
+		// batch whatever is queued up into one write
+		if len(c.out) == 0 {
+			return w.Flush() == nil
+		}
+		return true
+	}
+	for {
+		select {
+		case <-c.ctx.Done():
+			for {
+				select {
+				case line := <-c.out:
+					write(line)
+				default:
+					w.Flush()
+					return
+				}
+			}
+		case <-ping.C:
+			if !write("PING :" + IRCServerName) {
+				c.cancel()
+				c.conn.Close()
+				return
+			}
+		case line := <-c.out:
+			if !write(line) {
+				c.cancel()
+				// unblocks the read loop
+				c.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func (c *ircConn) send(format string, args ...interface{}) {
+	select {
+	case c.out <- fmt.Sprintf(format, args...):
+	case <-c.ctx.Done():
+	}
+}
+
+// trySend is used from event listeners and never blocks.
+func (c *ircConn) trySend(line string) {
+	select {
+	case c.out <- line:
+	default:
+		c.i.log.Warn("queue full, dropping line", "remote_addr", c.conn.RemoteAddr().String())
+		messagesDropped.WithLabelValues("irc").Inc()
+	}
+}
+
+// reply sends a numeric reply to the client.
+func (c *ircConn) reply(numeric string, params ...string) {
+	nick := c.nick
+	if nick == "" {
+		nick = "*"
+	}
+	if n := len(params); n > 0 {
+		params[n-1] = ":" + params[n-1]
+	}
+	c.send(":%s %s %s %s", IRCServerName, numeric, nick, strings.Join(params, " "))
+}
+
+// replyError maps a chat error onto the closest IRC error reply.
+func (c *ircConn) replyError(target string, err error) {
+	st := status.Convert(err)
+	isRoom := strings.HasPrefix(target, "#")
+	switch {
+	case st.Code() == codes.NotFound && isRoom:
+		c.reply("403", target, "No such channel")
+	case st.Code() == codes.NotFound:
+		c.reply("401", target, "No such nick/channel")
+	case st.Code() == codes.AlreadyExists:
+		c.reply("433", target, "Nickname is already in use")
+	case isRoom && st.Code() == codes.PermissionDenied:
+		c.reply("404", target, st.Message())
+	default:
+		c.send(":%s NOTICE %s :%s", IRCServerName, c.nick, st.Message())
+	}
+}
+
+// handle runs one command, it returns false when the client quit.
+func (c *ircConn) handle(m ircMessage) bool {
+	switch m.command {
+	case "CAP":
+		// no capabilities are supported
+		if len(m.params) > 0 && strings.ToUpper(m.params[0]) == "LS" {
+			c.send(":%s CAP * LS :", IRCServerName)
+		}
+		return true
+	case "PASS":
+		return true
+	case "PING":
+		c.send(":%s PONG %s :%s", IRCServerName, IRCServerName, strings.Join(m.params, " "))
+		return true
+	case "PONG":
+		return true
+	case "QUIT":
+		c.send("ERROR :Closing link")
+		return false
+	case "NICK":
+		c.handleNick(m)
+		return true
+	case "USER":
+		if c.client != nil {
+			c.reply("462", "You may not reregister")
+			return true
+		}
+		if len(m.params) < 4 {
+			c.reply("461", "USER", "Not enough parameters")
+			return true
+		}
+		c.user = m.params[0]
+		c.register()
+		return true
+	}
+
+	if c.client == nil {
+		c.reply("451", "You have not registered")
+		return true
+	}
+	switch m.command {
+	case "PRIVMSG", "NOTICE":
+		c.handlePrivmsg(m)
+	case "JOIN":
+		c.handleJoin(m)
+	case "PART":
+		c.handlePart(m)
+	case "NAMES":
+		c.handleNames(m)
+	case "WHO":
+		c.handleWho(m)
+	case "TOPIC":
+		c.handleTopic(m)
+	case "LIST":
+		c.handleList()
+	case "MODE":
+		// modes are not supported, just keep clients happy
+		if len(m.params) > 0 && strings.HasPrefix(m.params[0], "#") {
+			c.reply("324", m.params[0], "+")
+		} else {
+			c.reply("221", "+")
+		}
+	default:
+		c.reply("421", m.command, "Unknown command")
+	}
+	return true
+}
+
+func (c *ircConn) handleNick(m ircMessage) {
+	if len(m.params) == 0 || m.params[0] == "" {
+		c.reply("431", "No nickname given")
+		return
+	}
+	nick := m.params[0]
+	if nick != ircNick(nick) || strings.HasPrefix(nick, "#") {
+		c.reply("432", nick, "Erroneous nickname")
+		return
+	}
+	if err := validateName("name", nick); err != nil {
+		c.reply("432", nick, "Erroneous nickname: "+status.Convert(err).Message())
+		return
+	}
+	if other, err := c.i.findNick(nick); status.Code(err) != codes.NotFound && (c.client == nil || other != c.client) {
+		c.reply("433", nick, "Nickname is already in use")
+		return
+	}
+	if c.client == nil {
+		c.nick = nick
+		c.register()
+		return
+	}
+	old, err := c.i.s.rename(c.client, nick)
+	if err != nil {
+		c.reply("433", nick, "Nickname is already in use")
+		return
+	}
+	c.send(":%s NICK :%s", ircPrefix(old), nick)
+	c.nick = nick
+}
+
+// register connects once both NICK and USER were sent.
+func (c *ircConn) register() {
+	if c.nick == "" || c.user == "" || c.client != nil {
+		return
+	}
+	resp, err := c.i.s.Connect(c.ctx, &pb.ConnectRequest{Name: c.nick})
+	if err != nil {
+		c.replyError(c.nick, err)
+		return
+	}
+	client, err := c.i.s.subscribe(resp.GetClientId())
+	if err != nil {
+		// nothing would ever disconnect the client otherwise
+		c.i.s.Disconnect(c.ctx, &pb.DisconnectRequest{ClientId: resp.GetClientId()})
+		c.replyError(c.nick, err)
+		return
+	}
+	c.client = client
+	c.i.mu.Lock()
+	c.i.conns[client.clientId] = c
+	c.i.mu.Unlock()
+	go c.receive()
+
+	c.reply("001", fmt.Sprintf("Welcome to %s, %s", IRCServerName, c.nick))
+	c.reply("002", fmt.Sprintf("Your host is %s", IRCServerName))
+	c.reply("003", "This server speaks a subset of IRC")
+	c.reply("004", IRCServerName, "go-chat", "o", "t")
+	c.reply("005", "CHANTYPES=#", fmt.Sprintf("NICKLEN=%d", MaxNameLength), "are supported by this server")
+	c.reply("422", "MOTD File is missing")
+}
+
+// receive turns what the server queued for the client into IRC lines.
+// When the server shuts down it sends what is left and closes the link.
+func (c *ircConn) receive() {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.i.s.goingAway:
+			c.drain()
+			c.send("ERROR :Closing link (%s)", goingAwayReason)
+			c.cancel()
+			return
+		case m := <-c.client.messageCh:
+			c.deliver(m)
+		case ev := <-c.client.eventCh:
+			// presence is shown through channel JOIN and PART, own
+			// renames are answered by handleNick
+			if r := ev.GetRenamed(); r != nil && r.GetClient().GetId() != c.client.clientId.String() {
+				c.send(":%s NICK :%s", ircPrefix(r.GetOldName()), ircNick(r.GetClient().GetName()))
+			}
+		}
+	}
+}
+
+// drain sends what is still queued for the client.
+func (c *ircConn) drain() {
+	for {
+		select {
+		case m := <-c.client.messageCh:
+			c.deliver(m)
+		default:
+			return
+		}
+	}
+}
+
+func (c *ircConn) deliver(m chatMessage) {
+	span := startDeliverySpan(m, "irc")
+	c.sendMessage(m)
+	span.End()
+	messagesDelivered.WithLabelValues("irc").Inc()
+}
+
+func (c *ircConn) sendMessage(m chatMessage) {
+	prefix := ircPrefix(IRCServerName)
+	if name := m.metadata["display_name"]; name != "" && m.sender == systemSender.String() {
+		prefix = ircPrefix(name)
+	}
+	c.i.s.clientsMu.Lock()
+	if id, err := uuid.Parse(m.sender); err == nil && id != systemSender {
+		if sender, ok := c.i.s.clients[id]; ok {
+			prefix = ircPrefix(sender.name)
+		}
+	}
+	// c.nick belongs to the read loop
+	target := ircNick(c.client.name)
+	c.i.s.clientsMu.Unlock()
+	if m.room != "" {
+		target = m.room
+	}
+	for _, line := range strings.Split(m.text, "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			c.send(":%s PRIVMSG %s :%s", prefix, target, line)
+		}
+	}
+}
+
+func (c *ircConn) handlePrivmsg(m ircMessage) {
+	// NOTICE must never be answered with an error
+	notice := m.command == "NOTICE"
+	if len(m.params) == 0 {
+		if !notice {
+			c.reply("411", "No recipient given (PRIVMSG)")
+		}
+		return
+	}
+	if len(m.params) < 2 || m.params[1] == "" {
+		if !notice {
+			c.reply("412", "No text to send")
+		}
+		return
+	}
+	text := m.params[1]
+	// CTCP ACTION, sent by /me
+	if strings.HasPrefix(text, "\x01ACTION ") {
+		text = "/me " + strings.TrimSuffix(strings.TrimPrefix(text, "\x01ACTION "), "\x01")
+	}
+	for _, target := range strings.Split(m.params[0], ",") {
+		in := &pb.ChatMessage{SenderId: c.client.clientId.String(), Text: text}
+		if strings.HasPrefix(target, "#") {
+			in.Room = target
+		} else {
+			recipient, err := c.i.findNick(target)
+			if err != nil {
+				if !notice {
+					c.replyError(target, err)
+				}
+				continue
+			}
+			in.RecipientId = recipient.clientId.String()
+		}
+		if _, err := c.i.s.limiter.allow("irc:"+c.conn.RemoteAddr().String(), in); err != nil {
+			if !notice {
+				c.replyError(target, err)
+			}
+			continue
+		}
+		resp, err := c.i.s.Message(c.ctx, in)
+		if err != nil {
+			if !notice {
+				c.replyError(target, err)
+			}
+			continue
+		}
+		if out := resp.GetCommandOutput(); out != "" {
+			c.send(":%s NOTICE %s :%s", IRCServerName, c.nick, out)
+		}
+	}
+}
+
+func (c *ircConn) handleJoin(m ircMessage) {
+	if len(m.params) == 0 {
+		c.reply("461", "JOIN", "Not enough parameters")
+		return
+	}
+	for _, name := range strings.Split(m.params[0], ",") {
+		r, err := c.i.s.joinRoom(c.client, name)
+		if err != nil {
+			c.replyError(name, err)
+			continue
+		}
+		c.send(":%s JOIN %s", ircPrefix(c.nick), r.name)
+		c.sendTopic(r)
+		c.sendNames(r.name)
+	}
+}
+
+func (c *ircConn) handlePart(m ircMessage) {
+	if len(m.params) == 0 {
+		c.reply("461", "PART", "Not enough parameters")
+		return
+	}
+	for _, name := range strings.Split(m.params[0], ",") {
+		room, err := roomName(name)
+		if err != nil {
+			c.replyError(name, err)
+			continue
+		}
+		if err := c.i.s.leaveRoom(c.client, room); err != nil {
+			if status.Code(err) == codes.PermissionDenied {
+				c.reply("442", name, "You're not on that channel")
+			} else {
+				c.replyError(name, err)
+			}
+			continue
+		}
+		c.send(":%s PART %s", ircPrefix(c.nick), room)
+	}
+}
+
+func (c *ircConn) handleTopic(m ircMessage) {
+	if len(m.params) == 0 {
+		c.reply("461", "TOPIC", "Not enough parameters")
+		return
+	}
+	name := m.params[0]
+	if len(m.params) == 1 {
+		r, err := c.i.s.getRoom(name)
+		if err != nil {
+			c.replyError(name, err)
+			return
+		}
+		c.sendTopic(r)
+		return
+	}
+	room, err := roomName(name)
+	if err != nil {
+		c.replyError(name, err)
+		return
+	}
+	if err := c.i.s.setTopic(c.client, room, m.params[1]); err != nil {
+		if status.Code(err) == codes.PermissionDenied {
+			c.reply("442", name, "You're not on that channel")
+		} else {
+			c.replyError(name, err)
+		}
+		return
+	}
+	c.send(":%s TOPIC %s :%s", ircPrefix(c.nick), room, m.params[1])
+}
+
+func (c *ircConn) sendTopic(r room) {
+	if r.topic == "" {
+		c.reply("331", r.name, "No topic is set")
+		return
+	}
+	c.reply("332", r.name, r.topic)
+}
+
+func (c *ircConn) handleNames(m ircMessage) {
+	if len(m.params) == 0 {
+		c.reply("366", "*", "End of /NAMES list")
+		return
+	}
+	for _, name := range strings.Split(m.params[0], ",") {
+		c.sendNames(name)
+	}
+}
+
+func (c *ircConn) sendNames(name string) {
+	r, err := c.i.s.getRoom(name)
+	if err != nil {
+		c.reply("366", name, "End of /NAMES list")
+		return
+	}
+	names := r.memberNames()
+	for i := range names {
+		names[i] = ircNick(names[i])
+	}
+	// keep lines well below the 512 byte limit of older clients
+	for len(names) > 0 {
+		n := len(names)
+		if n > 20 {
+			n = 20
+		}
+		c.reply("353", "=", r.name, strings.Join(names[:n], " "))
+		names = names[n:]
+	}
+	c.reply("366", r.name, "End of /NAMES list")
+}
+
+func (c *ircConn) handleWho(m ircMessage) {
+	mask := "*"
+	if len(m.params) > 0 {
+		mask = m.params[0]
+	}
+	var members []*client
+	channel := "*"
+	switch {
+	case strings.HasPrefix(mask, "#"):
+		if r, err := c.i.s.getRoom(mask); err == nil {
+			channel = r.name
+			for _, member := range r.members {
+				members = append(members, member)
+			}
+		}
+	case mask == "*" || mask == "0":
+		c.i.s.clientsMu.Lock()
+		for _, client := range c.i.s.clients {
+			members = append(members, client)
+		}
+		c.i.s.clientsMu.Unlock()
+	default:
+		if client, err := c.i.findNick(mask); err == nil {
+			members = append(members, client)
+		}
+	}
+
+	c.i.s.clientsMu.Lock()
+	nicks := make([]string, 0, len(members))
+	for _, member := range members {
+		nicks = append(nicks, ircNick(member.name))
+	}
+	c.i.s.clientsMu.Unlock()
+	sort.Strings(nicks)
+	for _, nick := range nicks {
+		c.reply("352", channel, nick, IRCServerName, IRCServerName, nick, "H", "0 "+nick)
+	}
+	c.reply("315", mask, "End of /WHO list")
+}
+
+func (c *ircConn) handleList() {
+	c.i.s.clientsMu.Lock()
+	type entry struct {
+		name, topic string
+		members     int
+	}
+	rooms := make([]entry, 0, len(c.i.s.rooms))
+	for _, r := range c.i.s.rooms {
+		rooms = append(rooms, entry{r.name, r.topic, len(r.members)})
+	}
+	c.i.s.clientsMu.Unlock()
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].name < rooms[j].name })
+
+	c.reply("321", "Channel", "Users  Name")
+	for _, r := range rooms {
+		c.reply("322", r.name, fmt.Sprint(r.members), r.topic)
+	}
+	c.reply("323", "End of /LIST")
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDHeader is read from the caller's metadata, or generated, and
+// sent back in the response header.
+const requestIDHeader = "x-request-id"
+
+// loggers hands out the logger of each subsystem. Their levels default to
+// -log-level and can be set one by one with -log-levels. Loggers check
+// the level of their subsystem on every call, so setLevels also applies
+// to loggers derived from them.
+type loggers struct {
+	root   hclog.Logger
+	json   bool
+	output sync.Mutex
+
+	mu     sync.RWMutex
+	level  hclog.Level
+	levels map[string]hclog.Level
+}
+
+// newLoggers expects settings that passed validation.
+func newLoggers(settings logSettings) (*loggers, error) {
+	l := &loggers{json: settings.Format == "json"}
+	if err := l.setLevels(settings); err != nil {
+		return nil, err
+	}
+	l.root = l.named("")
+	hclog.SetDefault(l.root)
+	// whatever still logs through the standard logger ends up here too
+	log.SetFlags(0)
+	log.SetOutput(l.root.StandardWriter(&hclog.StandardLoggerOptions{InferLevels: true}))
+	return l, nil
+}
+
+func parseLevel(s string) (hclog.Level, error) {
+	lvl := hclog.LevelFromString(s)
+	if lvl == hclog.NoLevel {
+		return lvl, fmt.Errorf("invalid log level %q", s)
+	}
+	return lvl, nil
+}
+
+// setLevels replaces the level and subsystem levels of all loggers.
+func (l *loggers) setLevels(settings logSettings) error {
+	level, err := parseLevel(settings.Level)
+	if err != nil {
+		return err
+	}
+	levels := make(map[string]hclog.Level)
+	for name, s := range settings.Levels {
+		if levels[name], err = parseLevel(s); err != nil {
+			return err
+		}
+	}
+	l.mu.Lock()
+	l.level, l.levels = level, levels
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *loggers) enabled(name string, level hclog.Level) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	min, ok := l.levels[name]
+	if !ok {
+		min = l.level
+	}
+	return level >= min
+}
+
+func (l *loggers) named(name string) hclog.Logger {
+	return hclog.New(&hclog.LoggerOptions{
+		Name:       name,
+		Level:      hclog.Trace,
+		JSONFormat: l.json,
+		Output:     os.Stderr,
+		Mutex:      &l.output,
+		Exclude: func(level hclog.Level, msg string, args ...interface{}) bool {
+			return !l.enabled(name, level)
+		},
+	})
+}
+
+type loggerKey struct{}
+
+// requestLogger returns the logger of the request ctx belongs to, which
+// has the rpc, request id and client id as fields, or fallback outside of
+// requests.
+func requestLogger(ctx context.Context, fallback hclog.Logger) hclog.Logger {
+	if lg, ok := ctx.Value(loggerKey{}).(hclog.Logger); ok {
+		return lg
+	}
+	return fallback
+}
+
+func withLogger(ctx context.Context, lg hclog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, lg)
+}
+
+// startRequest picks the request id and returns the request's logger.
+func startRequest(ctx context.Context, base hclog.Logger, method string) (string, hclog.Logger) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var id string
+	if v := md.Get(requestIDHeader); len(v) > 0 && v[0] != "" {
+		id = v[0]
+	} else {
+		id = uuid.NewString()
+	}
+	return id, base.With("rpc", method, "request_id", id)
+}
+
+// requestClient is implemented by the requests that name their client.
+type requestClient interface {
+	GetClientId() string
+}
+
+type requestSender interface {
+	GetSenderId() string
+}
+
+func loggingUnaryInterceptor(base hclog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		id, lg := startRequest(ctx, base, info.FullMethod)
+		switch r := req.(type) {
+		case requestClient:
+			if r.GetClientId() != "" {
+				lg = lg.With("client_id", r.GetClientId())
+			}
+		case requestSender:
+			if r.GetSenderId() != "" {
+				lg = lg.With("client_id", r.GetSenderId())
+			}
+		}
+		grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, id))
+		start := time.Now()
+		resp, err := handler(withLogger(ctx, lg), req)
+		logFinished(lg, start, err)
+		return resp, err
+	}
+}
+
+func loggingStreamInterceptor(base hclog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		id, lg := startRequest(ss.Context(), base, info.FullMethod)
+		ss.SetHeader(metadata.Pairs(requestIDHeader, id))
+		start := time.Now()
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: withLogger(ss.Context(), lg)})
+		logFinished(lg, start, err)
+		return err
+	}
+}
+
+// logFinished logs failures of the server at error level, everything else
+// is only of interest when debugging.
+func logFinished(lg hclog.Logger, start time.Time, err error) {
+	code := status.Code(err)
+	switch code {
+	case codes.Unknown, codes.Internal, codes.DataLoss:
+		lg.Error("request failed", "code", code.String(), "duration", time.Since(start), "error", err)
+	default:
+		lg.Debug("request finished", "code", code.String(), "duration", time.Since(start))
+	}
+}
@@ -2,22 +2,63 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
-const ListenAddr = "localhost:8081"
+// subscribedHeader is sent by the Receive streams once the subscription
+// was accepted. A failed stream has no headers of its own, grpc reports
+// its empty trailers instead, so clients check for this key.
+const subscribedHeader = "gochat-subscribed"
+
+// ResumeGrace is how long a client is kept after its stream ends, so it
+// can subscribe again with the same id.
+const ResumeGrace = 30 * time.Second
 
 type client struct {
 	clientId  uuid.UUID
 	name      string
 	messageCh chan chatMessage
+	eventCh   chan *pb.Event
+	// replica holding the client's stream, see cluster.go
+	node string
+	// replaced, never modified, see updateProfile
+	profile *pb.Profile
+	// set while a Receive stream is open, a client has at most one
+	streaming bool
+	// disconnects the client once its stream ended, see release
+	idle *time.Timer
+
+	// set for users of a federated server, messages to them are relayed
+	peer     *federationPeer
+	remoteId string
 }
 
 func (c client) String() string {
@@ -30,13 +71,76 @@ func getClientById(id uuid.UUID, clients map[uuid.UUID]*client) (*client, error)
 			return c, nil
 		}
 	}
-	return nil, fmt.Errorf("no such client: %s", id)
+	return nil, notFound("client", id.String())
+}
+
+// findLocalClient is getClientById for clients of this server, not of a
+// federated one. It must be called with clientsMu held.
+func (s *server) findLocalClient(id string) (*client, error) {
+	clientId, err := parseID("recipient_id", id)
+	if err != nil {
+		return nil, err
+	}
+	c, err := getClientById(clientId, s.clients)
+	if err != nil {
+		return nil, err
+	}
+	if c.peer != nil {
+		return nil, notFound("client", id)
+	}
+	return c, nil
 }
 
+// getClientByName finds a client by name, which may start with @ and is
+// matched regardless of case.
+func (s *server) getClientByName(name string) (*client, error) {
+	name = strings.TrimPrefix(name, "@")
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	var found *client
+	for _, c := range s.clients {
+		if !strings.EqualFold(c.name, name) {
+			continue
+		}
+		if found != nil {
+			return nil, status.Errorf(codes.FailedPrecondition, "more than one client named %s", name)
+		}
+		found = c
+	}
+	if found == nil {
+		return nil, notFound("client", name)
+	}
+	return found, nil
+}
+
+// nameTaken reports whether a client other than except goes by name,
+// regardless of case. It must be called with clientsMu held.
+func (s *server) nameTaken(name string, except *client) bool {
+	for _, c := range s.clients {
+		if c != except && strings.EqualFold(c.name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func errNameTaken(name string) error {
+	return alreadyExists("client", name, "name is taken")
+}
+
+// systemSender is the sender id of messages sent by the server itself,
+// for example by scripts.
+var systemSender = uuid.Nil
+
 type chatMessage struct {
 	recipient string
+	room      string
 	sender    string
 	text      string
+	metadata  map[string]string
+	// trace context of the sender's span, kept out of metadata so it
+	// is not handed to clients, webhooks or peers
+	trace map[string]string
 }
 
 type server struct {
@@ -44,36 +148,208 @@ type server struct {
 	clientCount   int32
 	clientCountMu sync.Mutex
 
+	// clientsMu guards both clients and rooms
 	clients   map[uuid.UUID]*client
+	rooms     map[string]*room
 	clientsMu sync.Mutex
+
+	commands  *commandRegistry
+	listeners []eventListener
+
+	// pipelineMu guards pipeline, which reload replaces
+	pipeline   pipeline
+	pipelineMu sync.RWMutex
+	// set when -scripts is given
+	scripts *scriptEngine
+
+	webhooks   *webhookDispatcher
+	webhooksMu sync.RWMutex
+
+	limiter *rateLimiter
+	// sent by the gateway over the self-dial, see rateLimiter.connection
+	selfToken string
+
+	node       string
+	broker     Broker
+	roomSubsMu sync.Mutex
+	clusterLog hclog.Logger
+	// set in raft cluster mode
+	replicator *replicator
+
+	// cfgMu guards cfg, read it with config
+	cfg      *config
+	cfgMu    sync.RWMutex
+	reloadMu sync.Mutex
+	// credentials other replicas and peers are dialed with
+	dialCreds grpc.DialOption
+
+	logs *loggers
+	log  hclog.Logger
+
+	// closed once the server starts shutting down
+	goingAway chan struct{}
 }
 
 func (s *server) Connect(ctx context.Context, in *pb.ConnectRequest) (*pb.ConnectResponse, error) {
+	// not held any longer, Subscribe below may be a round trip to the broker
 	s.clientCountMu.Lock()
-	defer s.clientCountMu.Unlock()
 	s.clientCount += 1
+	s.clientCountMu.Unlock()
 
+	if s.isGoingAway() {
+		return nil, errGoingAway()
+	}
+	if err := validateName("name", in.GetName()); err != nil {
+		return nil, err
+	}
+	s.clientsMu.Lock()
+	taken := s.nameTaken(in.GetName(), nil)
+	s.clientsMu.Unlock()
+	if taken {
+		return nil, errNameTaken(in.GetName())
+	}
 	id, err := uuid.NewRandom()
 	if err != nil {
-		log.Fatalf("could not generate uuid: %v\n", err)
+		return nil, status.Errorf(codes.Internal, "could not generate client id: %v", err)
 	}
 	c := client{
 		clientId:  id,
 		name:      in.GetName(),
-		messageCh: make(chan chatMessage, 100),
+		messageCh: make(chan chatMessage, s.config().Limits.MessageQueue),
+		eventCh:   make(chan *pb.Event, s.config().Limits.EventQueue),
+		node:      s.node,
+	}
+	if err := s.broker.Subscribe(userTopic(id)); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "could not subscribe: %v", err)
+	}
+	s.clientsMu.Lock()
+	if s.nameTaken(c.name, nil) {
+		// taken while subscribing
+		s.clientsMu.Unlock()
+		s.broker.Unsubscribe(userTopic(id))
+		return nil, errNameTaken(c.name)
 	}
 	s.clients[c.clientId] = &c
-	log.Printf("client %s connected\n", c)
+	s.broadcastPresence(&c, pb.PresenceEvent_ONLINE)
+	s.clientsMu.Unlock()
+	s.publishClient(c, true)
+	requestLogger(ctx, s.log).Info("client connected", "client_id", id, "name", c.name)
+	s.emit(serverEvent{kind: clientConnected, client: c})
 	return &pb.ConnectResponse{ClientId: id.String()}, nil
 }
 
+// Disconnect ends a client right away, instead of once its stream ended
+// and it did not subscribe again within retention.disconnected_clients.
+func (s *server) Disconnect(ctx context.Context, in *pb.DisconnectRequest) (*pb.DisconnectResponse, error) {
+	id, err := parseID("client_id", in.GetClientId())
+	if err != nil {
+		return nil, err
+	}
+	s.clientsMu.Lock()
+	c, err := s.findLocalClient(id.String())
+	var elsewhere bool
+	if err == nil {
+		elsewhere = c.node != s.node
+	}
+	s.clientsMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if elsewhere {
+		return nil, status.Errorf(codes.FailedPrecondition, "client %s is served by another replica", id)
+	}
+	s.disconnect(c)
+	return &pb.DisconnectResponse{}, nil
+}
+
+// disconnect removes the client and lets everyone else know it is gone.
+func (s *server) disconnect(c *client) {
+	s.clientsMu.Lock()
+	moved := c.peer == nil && c.node != s.node
+	s.clientsMu.Unlock()
+	if moved {
+		// the client subscribed on another replica since
+		return
+	}
+	gone, left, ok := s.removeClient(c)
+	if !ok {
+		return
+	}
+	if gone.peer == nil {
+		s.broker.Unsubscribe(userTopic(gone.clientId))
+		s.publishClient(gone, false)
+	}
+	for _, name := range left {
+		s.syncRoomSubscription(name)
+	}
+
+	s.log.Info("client disconnected", "client_id", gone.clientId, "name", gone.name)
+	for _, name := range left {
+		s.emit(serverEvent{kind: roomLeft, client: gone, room: name})
+	}
+	s.emit(serverEvent{kind: clientDisconnected, client: gone})
+}
+
+// removeClient removes c from the clients and its rooms and tells the
+// local clients. It returns a copy of c and the rooms it left.
+func (s *server) removeClient(c *client) (client, []string, bool) {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	if _, ok := s.clients[c.clientId]; !ok {
+		return client{}, nil, false
+	}
+	delete(s.clients, c.clientId)
+	left := s.roomsOf(c)
+	for _, name := range left {
+		s.removeMember(s.rooms[name], c)
+	}
+	s.broadcastPresence(c, pb.PresenceEvent_OFFLINE)
+	return *c, left, true
+}
+
+// broadcastPresence must be called with clientsMu held.
+func (s *server) broadcastPresence(subject *client, st pb.PresenceEvent_Status) {
+	ev := &pb.Event{Event: &pb.Event_Presence{Presence: presenceEvent(subject, st)}}
+	for _, c := range s.clients {
+		if c.clientId == subject.clientId || !s.isLocal(c) {
+			continue
+		}
+		select {
+		case c.eventCh <- ev:
+		default:
+			s.log.Warn("event queue full, dropping presence event", "client_id", c.clientId)
+			messagesDropped.WithLabelValues("events").Inc()
+		}
+	}
+}
+
+// broadcastRename tells local clients, subject included, that it was
+// called old. It must be called with clientsMu held.
+func (s *server) broadcastRename(subject *client, old string) {
+	ev := &pb.Event{Event: &pb.Event_Renamed{Renamed: &pb.RenameEvent{
+		Client:  connectedClient(subject),
+		OldName: old,
+	}}}
+	for _, c := range s.clients {
+		if !s.isLocal(c) {
+			continue
+		}
+		select {
+		case c.eventCh <- ev:
+		default:
+			s.log.Warn("event queue full, dropping rename event", "client_id", c.clientId)
+			messagesDropped.WithLabelValues("events").Inc()
+		}
+	}
+}
+
 func (s *server) GetConnectedClients(ctx context.Context, in *pb.ConnectedClientsRequest) (*pb.ConnectedClientsResponse, error) {
 	s.clientsMu.Lock()
 	defer s.clientsMu.Unlock()
 
 	clients := []*pb.ConnectedClientsResponse_ConnectedClient{}
 	for _, client := range s.clients {
-		clients = append(clients, &pb.ConnectedClientsResponse_ConnectedClient{Name: client.name, Id: client.clientId.String()})
+		clients = append(clients, connectedClient(client))
 	}
 
 	resp := pb.ConnectedClientsResponse{Clients: clients}
@@ -81,54 +357,623 @@ func (s *server) GetConnectedClients(ctx context.Context, in *pb.ConnectedClient
 	return &resp, nil
 }
 
-func (s *server) Message(ctx context.Context, in *pb.ChatMessage) (*pb.MessageResponse, error) {
+func (s *server) ResolveUser(ctx context.Context, in *pb.ResolveUserRequest) (*pb.ResolveUserResponse, error) {
+	name := strings.TrimPrefix(in.GetName(), "@")
+	if name == "" {
+		return nil, invalidField("name", "is required")
+	}
+	c, err := s.getClientByName(name)
+	if err != nil {
+		return nil, err
+	}
 	s.clientsMu.Lock()
 	defer s.clientsMu.Unlock()
+	return &pb.ResolveUserResponse{Client: connectedClient(c)}, nil
+}
 
-	recipient, err := getClientById(uuid.MustParse(in.RecipientId), s.clients)
+func (s *server) ChangeName(ctx context.Context, in *pb.ChangeNameRequest) (*pb.ChangeNameResponse, error) {
+	id, err := parseID("client_id", in.GetClientId())
+	if err != nil {
+		return nil, err
+	}
+	if err := validateName("name", in.GetName()); err != nil {
+		return nil, err
+	}
+	s.clientsMu.Lock()
+	c, err := getClientById(id, s.clients)
+	s.clientsMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if c.peer != nil {
+		return nil, permissionDenied("REMOTE_USER", "can not rename a user of another server")
+	}
+	old, err := s.rename(c, in.GetName())
+	if err != nil {
+		return nil, err
+	}
+	requestLogger(ctx, s.log).Info("client renamed", "old_name", old, "name", in.GetName())
+	return &pb.ChangeNameResponse{OldName: old}, nil
+}
+
+func (s *server) Message(ctx context.Context, in *pb.ChatMessage) (*pb.MessageResponse, error) {
+	senderId, err := parseID("sender_id", in.GetSenderId())
 	if err != nil {
 		return nil, err
 	}
-	sender, err := getClientById(uuid.MustParse(in.SenderId), s.clients)
+	if err := validateChatMessage(in, s.config().Limits.MaxMessageLength); err != nil {
+		return nil, err
+	}
+	s.clientsMu.Lock()
+	sender, err := getClientById(senderId, s.clients)
+	s.clientsMu.Unlock()
 	if err != nil {
 		return nil, err
 	}
+	if sender.peer != nil {
+		return nil, permissionDenied("REMOTE_SENDER", "can not send as a user of another server")
+	}
+
+	m := chatMessage{recipient: in.GetRecipientId(), room: in.GetRoom(), text: in.GetText(), sender: sender.clientId.String()}
+	if m.room != "" {
+		if m.room, err = roomName(m.room); err != nil {
+			return nil, err
+		}
+	}
+	if name := in.GetRecipientName(); name != "" {
+		recipient, err := s.getClientByName(name)
+		if err != nil {
+			return nil, err
+		}
+		m.recipient = recipient.clientId.String()
+	}
+	if cmd, ok := s.lookupCommand(m.text); ok {
+		out, err := s.runCommand(ctx, cmd, sender, m)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.MessageResponse{CommandOutput: out}, nil
+	}
+	if m.recipient == "" && m.room == "" {
+		return nil, invalidField("recipient_id", "one of recipient_id, recipient_name or room is required")
+	}
+	m.text = unescapeCommand(m.text)
 
-	recipient.messageCh <- chatMessage{recipient: recipient.clientId.String(), text: in.GetText(), sender: sender.clientId.String()}
+	if err := s.send(ctx, m); err != nil {
+		return nil, err
+	}
 	return &pb.MessageResponse{}, nil
 }
 
-func (s *server) ReceiveMessages(in *pb.ReceiveRequest, stream pb.ChatServer_ReceiveMessagesServer) error {
+// send runs m through the middleware pipeline and delivers it.
+func (s *server) send(ctx context.Context, m chatMessage) error {
+	s.pipelineMu.RLock()
+	p := s.pipeline
+	s.pipelineMu.RUnlock()
+	if err := p.process(ctx, &m); err != nil {
+		return err
+	}
+	if err := s.deliver(ctx, m); err != nil {
+		return err
+	}
+	ev := serverEvent{kind: messageSent, message: &m, room: m.room}
+	if id, err := uuid.Parse(m.sender); err == nil {
+		s.clientsMu.Lock()
+		if c, ok := s.clients[id]; ok {
+			ev.client = *c
+		}
+		s.clientsMu.Unlock()
+	}
+	s.emit(ev)
+	return nil
+}
+
+// deliver publishes m to the topic of its recipient or room, the replicas
+// subscribed to it queue it for their clients.
+func (s *server) deliver(ctx context.Context, m chatMessage) (err error) {
+	ctx, span := tracer.Start(ctx, "deliver", trace.WithAttributes(
+		attribute.String("gochat.recipient", m.recipient),
+		attribute.String("gochat.room", m.room),
+	))
+	defer func() { endSpan(span, err) }()
+	injectTrace(ctx, &m)
+
+	topic, err := s.route(m)
+	if err != nil || topic == "" {
+		return err
+	}
+	if err := s.publish(ctx, topic, &pb.BrokerEnvelope{Payload: &pb.BrokerEnvelope_Message{Message: m.toProto()}, Trace: m.trace}); err != nil {
+		return err
+	}
+	kind := "direct"
+	if m.room != "" {
+		kind = "room"
+	}
+	messagesSent.WithLabelValues(kind).Inc()
+	return nil
+}
+
+// route checks m can be sent and returns the topic to publish it to.
+// Messages to users of a federated server are relayed instead.
+func (s *server) route(m chatMessage) (string, error) {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+
+	if m.room != "" {
+		r, ok := s.rooms[m.room]
+		if !ok {
+			return "", notFound("room", m.room)
+		}
+		senderId, err := parseID("sender_id", m.sender)
+		if err != nil {
+			return "", err
+		}
+		if _, ok := r.members[senderId]; !ok && senderId != systemSender {
+			return "", permissionDenied("NOT_A_MEMBER", "not a member of %s", m.room)
+		}
+		return roomTopic(m.room), nil
+	}
+
+	id, err := parseID("recipient_id", m.recipient)
+	if err != nil {
+		return "", err
+	}
+	recipient, err := getClientById(id, s.clients)
+	if err != nil {
+		return "", err
+	}
+	if recipient.peer != nil {
+		s.enqueue(recipient, m)
+		messagesSent.WithLabelValues("federated").Inc()
+		return "", nil
+	}
+	return userTopic(id), nil
+}
+
+// enqueue never blocks, a client that does not keep up loses messages.
+// It is called with clientsMu held.
+func (s *server) enqueue(c *client, m chatMessage) {
+	if c.peer != nil {
+		c.peer.relay(c, m)
+		return
+	}
+	select {
+	case c.messageCh <- m:
+	default:
+		s.log.Warn("message queue full, dropping message", "client_id", c.clientId)
+		messagesDropped.WithLabelValues("messages").Inc()
+	}
+}
+
+func (m chatMessage) toProto() *pb.ChatMessage {
+	return &pb.ChatMessage{Text: m.text, SenderId: m.sender, RecipientId: m.recipient, Room: m.room, Metadata: m.metadata}
+}
+
+func chatMessageFromProto(m *pb.ChatMessage) chatMessage {
+	return chatMessage{text: m.GetText(), sender: m.GetSenderId(), recipient: m.GetRecipientId(), room: m.GetRoom(), metadata: m.GetMetadata()}
+}
+
+// subscribe returns the client to stream to, taking it over if it
+// connected through another replica. The stream must release it.
+func (s *server) subscribe(clientId string) (*client, error) {
+	if s.isGoingAway() {
+		return nil, errGoingAway()
+	}
+	id, err := parseID("client_id", clientId)
+	if err != nil {
+		return nil, err
+	}
+	s.clientsMu.Lock()
+	c, err := s.findLocalClient(id.String())
+	if err != nil {
+		s.clientsMu.Unlock()
+		return nil, err
+	}
+	if c.streaming {
+		s.clientsMu.Unlock()
+		return nil, status.Errorf(codes.FailedPrecondition, "client %s already has a receive stream", id)
+	}
+	c.streaming = true
+	if c.idle != nil {
+		// resumed within the grace period
+		c.idle.Stop()
+		c.idle = nil
+	}
+	if c.node == s.node {
+		s.clientsMu.Unlock()
+		return c, nil
+	}
+	c.node = s.node
+	if c.messageCh == nil {
+		c.messageCh = make(chan chatMessage, s.config().Limits.MessageQueue)
+		c.eventCh = make(chan *pb.Event, s.config().Limits.EventQueue)
+	}
+	adopted := *c
+	rooms := s.roomsOf(c)
+	s.clientsMu.Unlock()
+
+	if err := s.broker.Subscribe(userTopic(id)); err != nil {
+		s.clientsMu.Lock()
+		c.streaming = false
+		s.clientsMu.Unlock()
+		return nil, status.Errorf(codes.Unavailable, "could not subscribe: %v", err)
+	}
+	s.publishClient(adopted, true)
+	for _, name := range rooms {
+		s.syncRoomSubscription(name)
+	}
+	s.log.Info("client moved to this node", "client_id", adopted.clientId, "name", adopted.name)
+	return c, nil
+}
+
+// release ends the stream of c. Unless c subscribes again within
+// retention.disconnected_clients it is disconnected, so clients survive
+// a dropped connection with their id and rooms.
+func (s *server) release(c *client) {
+	grace := s.config().Retention.DisconnectedClients
 	s.clientsMu.Lock()
-	receiver, err := getClientById(uuid.MustParse(in.ClientId), s.clients)
+	c.streaming = false
+	if grace == 0 {
+		s.clientsMu.Unlock()
+		s.disconnect(c)
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(grace, func() {
+		s.clientsMu.Lock()
+		expired := c.idle == timer
+		if expired {
+			c.idle = nil
+		}
+		s.clientsMu.Unlock()
+		if expired {
+			s.disconnect(c)
+		}
+	})
+	c.idle = timer
 	s.clientsMu.Unlock()
+}
+
+func (s *server) ReceiveMessages(in *pb.ReceiveRequest, stream pb.ChatServer_ReceiveMessagesServer) error {
+	receiver, err := s.subscribe(in.ClientId)
 	if err != nil {
 		return err
 	}
+	defer s.release(receiver)
+	if err := stream.SendHeader(metadata.Pairs(subscribedHeader, "true")); err != nil {
+		return err
+	}
+	activeStreams.WithLabelValues("messages").Inc()
+	defer activeStreams.WithLabelValues("messages").Dec()
 
-	for m := range receiver.messageCh {
-		err := stream.Send(&pb.ChatMessage{Text: m.text, SenderId: m.sender, RecipientId: m.recipient})
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.goingAway:
+			return s.drainMessages(receiver, stream)
+		case m := <-receiver.messageCh:
+			if err := sendMessage(stream, m); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *server) ReceiveEvents(in *pb.ReceiveRequest, stream pb.ChatServer_ReceiveEventsServer) error {
+	receiver, err := s.subscribe(in.ClientId)
+	if err != nil {
+		return err
+	}
+	defer s.release(receiver)
+	if err := stream.SendHeader(metadata.Pairs(subscribedHeader, "true")); err != nil {
+		return err
+	}
+	activeStreams.WithLabelValues("events").Inc()
+	defer activeStreams.WithLabelValues("events").Dec()
+
+	for {
+		var err error
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.goingAway:
+			return s.goAway(receiver, stream)
+		case m := <-receiver.messageCh:
+			err = sendMessageEvent(stream, m)
+		case ev := <-receiver.eventCh:
+			err = stream.Send(ev)
+		}
 		if err != nil {
 			return err
 		}
 	}
+}
 
-	return nil
+func sendMessage(stream pb.ChatServer_ReceiveMessagesServer, m chatMessage) error {
+	span := startDeliverySpan(m, "grpc")
+	err := stream.Send(m.toProto())
+	endSpan(span, err)
+	if err == nil {
+		messagesDelivered.WithLabelValues("grpc").Inc()
+	}
+	return err
+}
+
+func sendMessageEvent(stream pb.ChatServer_ReceiveEventsServer, m chatMessage) error {
+	span := startDeliverySpan(m, "grpc")
+	err := stream.Send(&pb.Event{Event: &pb.Event_Message{Message: m.toProto()}})
+	endSpan(span, err)
+	if err == nil {
+		messagesDelivered.WithLabelValues("grpc").Inc()
+	}
+	return err
 }
 
 func main() {
-	listener, err := net.Listen("tcp", ListenAddr)
+	cfg, printConfig, err := loadConfig(os.Args[0], os.Args[1:])
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	if err == nil {
+		err = cfg.validate()
+	}
 	if err != nil {
-		log.Fatalf("can not listen: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if printConfig {
+		if err := cfg.print(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	logs, err := newLoggers(cfg.Log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid logging configuration: %v\n", err)
+		os.Exit(2)
+	}
+	mainLog := logs.named("main")
+	fatal := func(msg string, args ...interface{}) {
+		mainLog.Error(msg, args...)
+		os.Exit(1)
+	}
+
+	tlsConf, err := cfg.TLS.serverTLS()
+	if err != nil {
+		fatal("invalid tls configuration", "error", err)
+	}
+	dialCreds, err := cfg.TLS.dialOption()
+	if err != nil {
+		fatal("invalid tls configuration", "error", err)
+	}
+
+	listener, err := net.Listen("tcp", cfg.Listen.GRPC)
+	if err != nil {
+		fatal("can not listen", "addr", cfg.Listen.GRPC, "error", err)
+	}
+
+	s := server{
+		clients:   make(map[uuid.UUID]*client),
+		rooms:     make(map[string]*room),
+		commands:  newCommandRegistry(),
+		cfg:       cfg,
+		dialCreds: dialCreds,
+		selfToken: uuid.NewString(),
+		logs:      logs,
+		log:       logs.named("server"),
+		goingAway: make(chan struct{}),
+	}
+	s.clusterLog = logs.named("cluster")
+	s.limiter = newRateLimiter(&s, logs.named("ratelimit"))
+	go s.limiter.run(context.Background())
+	go s.expireStatuses(context.Background())
+	registerBuiltinCommands(s.commands)
+
+	grpcLog := logs.named("grpc")
+	grpcOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(tracingUnaryInterceptor, loggingUnaryInterceptor(grpcLog), metricsUnaryInterceptor, recoveryUnaryInterceptor(grpcLog), s.limiter.unaryInterceptor),
+		grpc.ChainStreamInterceptor(tracingStreamInterceptor, loggingStreamInterceptor(grpcLog), metricsStreamInterceptor, recoveryStreamInterceptor(grpcLog)),
+	}
+	if tlsConf != nil {
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsConf)))
+	}
+	grpcServer := grpc.NewServer(grpcOpts...)
+	s.registerMetrics(prometheus.DefaultRegisterer)
+
+	s.node = cfg.Node
+	if s.node == "" {
+		s.node = uuid.NewString()[:8]
+	}
+	brokerLog := logs.named("broker")
+	hub := newMemoryHub(brokerLog)
+	if cfg.Broker.Addr != "" {
+		s.broker, err = dialBroker(cfg.Broker.Addr, s.node, cfg.Broker.Secret, dialCreds, brokerLog)
+		if err != nil {
+			fatal("could not set up broker", "error", err)
+		}
+		mainLog.Info("using broker", "node", s.node, "addr", cfg.Broker.Addr)
+	} else {
+		s.broker = hub.attach(s.node)
+	}
+	if cfg.Broker.Hub {
+		pb.RegisterBrokerServer(grpcServer, &brokerHub{hub: hub, secret: cfg.Broker.Secret, log: brokerLog, goingAway: s.goingAway})
+		mainLog.Info("serving the broker", "node", s.node)
+	}
+	go s.routeEnvelopes(context.Background())
+
+	if cfg.Trace.OTLP != "" || cfg.Trace.File != "" {
+		shutdown, err := setupTracing(s.node, cfg.Trace.OTLP, cfg.Trace.File)
+		if err != nil {
+			fatal("could not set up tracing", "error", err)
+		}
+		defer shutdown(context.Background())
+	}
+
+	if cfg.Storage.Backend == "raft" {
+		if cfg.Broker.Addr == "" && !cfg.Broker.Hub {
+			mainLog.Warn("messages are only delivered on this replica without a broker address or hub")
+		}
+		dir := cfg.Storage.Raft.Dir
+		if dir == "" {
+			dir = filepath.Join("raft", s.node)
+		}
+		s.replicator, err = newReplicator(&s, raftConfig{
+			dir:            dir,
+			addr:           cfg.Storage.Raft.Addr,
+			grpcAddr:       listener.Addr().String(),
+			bootstrap:      cfg.Storage.Raft.Bootstrap,
+			secret:         cfg.Broker.Secret,
+			snapshotRetain: cfg.Retention.RaftSnapshots,
+			clientTimeout:  cfg.Retention.StaleClients,
+		})
+		if err != nil {
+			fatal("could not set up raft", "error", err)
+		}
+		pb.RegisterRaftServer(grpcServer, s.replicator)
+		s.replicator.run(context.Background(), cfg.Storage.Raft.Join)
+		mainLog.Info("replicating over raft", "node", s.node, "addr", cfg.Storage.Raft.Addr)
 	}
 
-	grpcServer := grpc.NewServer()
-	s := server{clients: make(map[uuid.UUID]*client)}
+	if cfg.Features.Scripts != "" {
+		s.scripts = newScriptEngine(&s, cfg.Features.Scripts)
+		s.addListener(s.scripts.handleEvent)
+		go s.scripts.run(context.Background())
+	}
+
+	s.addListener(s.notifyWebhooks)
+	if cfg.Features.Webhooks != "" {
+		configs, err := loadWebhookConfigs(cfg.Features.Webhooks)
+		if err != nil {
+			fatal("invalid webhooks configuration", "error", err)
+		}
+		s.setWebhooks(newWebhookDispatcher(configs, cfg.Features.WebhooksDeadLetter, logs.named("webhooks")))
+		mainLog.Info("sending events to webhooks", "count", len(configs))
+	}
+
+	if cfg.Features.Federation != "" {
+		fedCfg, err := loadFederationConfig(cfg.Features.Federation)
+		if err != nil {
+			fatal("invalid federation configuration", "error", err)
+		}
+		fed, err := newFederation(&s, fedCfg)
+		if err != nil {
+			fatal("could not set up federation", "error", err)
+		}
+		s.addListener(fed.handleEvent)
+		pb.RegisterFederationServer(grpcServer, fed)
+		fed.run(context.Background())
+		mainLog.Info("federating", "name", fedCfg.Name, "peers", len(fedCfg.Peers))
+	}
+
+	var irc *ircServer
+	if cfg.Listen.IRC != "" {
+		irc = newIRCServer(&s)
+		s.addListener(irc.handleEvent)
+	}
+
+	s.pipeline, err = s.pipelineFor(cfg)
+	if err != nil {
+		fatal("invalid middleware configuration", "error", err)
+	}
 	pb.RegisterChatServerServer(grpcServer, &s)
+	pb.RegisterAdminServer(grpcServer, &adminServer{s: &s})
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthService{Server: healthServer, goingAway: s.goingAway})
+
+	mux := http.NewServeMux()
+	if cfg.Features.Metrics {
+		mux.Handle("/metrics", promhttp.Handler())
+	}
+	if cfg.Features.Integrations != "" {
+		integrations, err := loadIntegrations(cfg.Features.Integrations)
+		if err != nil {
+			fatal("invalid integrations configuration", "error", err)
+		}
+		mux.Handle("/hooks/incoming", &incomingWebhookHandler{s: &s, integrations: integrations})
+	}
+	var httpServer *http.Server
+	if cfg.Listen.HTTP != "" {
+		// the gateway dials this process
+		selfCreds := grpc.WithTransportCredentials(insecure.NewCredentials())
+		if tlsConf != nil {
+			conf, err := selfTLS(tlsConf)
+			if err != nil {
+				fatal("invalid tls configuration", "error", err)
+			}
+			selfCreds = grpc.WithTransportCredentials(credentials.NewTLS(conf))
+		}
+		conn, err := grpc.Dial(listener.Addr().String(), selfCreds, grpc.WithUnaryInterceptor(s.selfTokenInterceptor))
+		if err != nil {
+			fatal("could not dial gateway connection", "error", err)
+		}
+		if cfg.Features.Gateway {
+			newGateway(conn).register(mux)
+		}
+		if cfg.Features.Websocket {
+			mux.Handle("/ws", newWebsocketHandler(conn, cfg.Features.WSOrigins, logs.named("websocket")))
+		}
+		httpServer = &http.Server{Addr: cfg.Listen.HTTP, Handler: mux, TLSConfig: tlsConf}
+		go func() {
+			mainLog.Info("started http server", "addr", cfg.Listen.HTTP)
+			var err error
+			if tlsConf != nil {
+				err = httpServer.ListenAndServeTLS("", "")
+			} else {
+				err = httpServer.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				fatal("cant serve http", "error", err)
+			}
+		}()
+	}
+
+	var ircListener net.Listener
+	if irc != nil {
+		ircListener, err = net.Listen("tcp", cfg.Listen.IRC)
+		if err != nil {
+			fatal("can not listen for irc", "addr", cfg.Listen.IRC, "error", err)
+		}
+		if tlsConf != nil {
+			ircListener = tls.NewListener(ircListener, tlsConf)
+		}
+		go func() {
+			mainLog.Info("started irc server", "addr", cfg.Listen.IRC)
+			if err := irc.serve(ircListener); err != nil && !errors.Is(err, net.ErrClosed) {
+				fatal("cant serve irc", "error", err)
+			}
+		}()
+	}
 
-	log.Printf("started server at %s\n", ListenAddr)
-	if err := grpcServer.Serve(listener); err != nil {
-		log.Fatalf("cant serve grpc: %v", err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			s.reload()
+		}
+	}()
+	served := make(chan error, 1)
+	go func() {
+		served <- grpcServer.Serve(listener)
+	}()
+	mainLog.Info("started server", "addr", cfg.Listen.GRPC, "node", s.node, "tls", tlsConf != nil)
+	select {
+	case err := <-served:
+		fatal("cant serve grpc", "error", err)
+	case <-ctx.Done():
 	}
+	// a second signal kills the process
+	stop()
 
+	timeout := s.config().ShutdownTimeout
+	mainLog.Info("shutting down", "timeout", timeout)
+	if ircListener != nil {
+		ircListener.Close()
+	}
+	s.shutdown(timeout, grpcServer, healthServer, httpServer)
+	mainLog.Info("stopped")
 }
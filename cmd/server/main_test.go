@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+)
+
+// newTestServer is a server with the default config that logs nothing,
+// without listeners or a broker.
+func newTestServer(t *testing.T) *server {
+	t.Helper()
+	return &server{
+		clients:    make(map[uuid.UUID]*client),
+		rooms:      make(map[string]*room),
+		commands:   newCommandRegistry(),
+		cfg:        defaultConfig(),
+		logs:       &loggers{level: hclog.Off},
+		log:        hclog.NewNullLogger(),
+		clusterLog: hclog.NewNullLogger(),
+		goingAway:  make(chan struct{}),
+	}
+}
+
+// addTestClient adds a local client named name to s.
+func addTestClient(s *server, name string) *client {
+	c := &client{clientId: uuid.New(), name: name}
+	s.clients[c.clientId] = c
+	return c
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Metrics are served on /metrics of the http listener.
+var (
+	messagesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gochat_messages_sent_total",
+		Help: "Messages accepted for delivery, by kind (direct, room, federated).",
+	}, []string{"kind"})
+	messagesDelivered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gochat_messages_delivered_total",
+		Help: "Messages written to a client stream, by transport (grpc, irc).",
+	}, []string{"transport"})
+	messagesDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gochat_messages_dropped_total",
+		Help: "Messages, envelopes and webhook deliveries dropped because a queue was full, by queue.",
+	}, []string{"queue"})
+	activeStreams = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gochat_streams",
+		Help: "Open receive streams, by kind (messages, events).",
+	}, []string{"kind"})
+	rpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gochat_grpc_request_duration_seconds",
+		Help:    "Latency of unary grpc requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+	rpcHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gochat_grpc_requests_total",
+		Help: "Finished grpc requests and streams, by method and status code.",
+	}, []string{"method", "code"})
+	rpcPanics = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gochat_grpc_panics_total",
+		Help: "Handlers that panicked and were answered with Internal, by method.",
+	}, []string{"method"})
+	rateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gochat_rate_limited_total",
+		Help: "Messages refused by a rate limit, by scope (session, user, room, muted).",
+	}, []string{"scope"})
+	storeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gochat_store_duration_seconds",
+		Help:    "Latency of broker publishes and raft proposals.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"store", "operation"})
+)
+
+// registerMetrics adds the metrics that are read from the server when
+// scraped.
+func (s *server) registerMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "gochat_connected_clients",
+		Help: "Clients with a stream on this replica.",
+	}, func() float64 {
+		s.clientsMu.Lock()
+		defer s.clientsMu.Unlock()
+		n := 0
+		for _, c := range s.clients {
+			if s.isLocal(c) {
+				n++
+			}
+		}
+		return float64(n)
+	}))
+	reg.MustRegister(&queueCollector{s: s})
+}
+
+// queueCollector reports how full the messageCh of local clients are. A
+// stuck client shows as a max depth at capacity.
+type queueCollector struct {
+	s *server
+}
+
+var (
+	queueDepthMax = prometheus.NewDesc("gochat_message_queue_depth_max",
+		"Largest number of messages queued for one client.", nil, nil)
+	queueDepthTotal = prometheus.NewDesc("gochat_message_queue_depth_total",
+		"Messages queued for all clients of this replica.", nil, nil)
+	queueFull = prometheus.NewDesc("gochat_message_queues_full",
+		"Clients whose message queue is full.", nil, nil)
+)
+
+func (q *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepthMax
+	ch <- queueDepthTotal
+	ch <- queueFull
+}
+
+func (q *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	q.s.clientsMu.Lock()
+	var max, total, full int
+	for _, c := range q.s.clients {
+		if !q.s.isLocal(c) {
+			continue
+		}
+		n := len(c.messageCh)
+		total += n
+		if n > max {
+			max = n
+		}
+		if n == cap(c.messageCh) {
+			full++
+		}
+	}
+	q.s.clientsMu.Unlock()
+	ch <- prometheus.MustNewConstMetric(queueDepthMax, prometheus.GaugeValue, float64(max))
+	ch <- prometheus.MustNewConstMetric(queueDepthTotal, prometheus.GaugeValue, float64(total))
+	ch <- prometheus.MustNewConstMetric(queueFull, prometheus.GaugeValue, float64(full))
+}
+
+func metricsUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	rpcDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+	rpcHandled.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+	return resp, err
+}
+
+func metricsStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := handler(srv, ss)
+	rpcHandled.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+	return err
+}
+
+// observeStore records how long an operation of the broker or raft took.
+func observeStore(store, operation string, start time.Time) {
+	storeDuration.WithLabelValues(store, operation).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// middleware sees every message between Message and delivery. It may
+// rewrite the text, attach metadata or veto delivery by returning an
+// error, which is passed on to the sender.
+type middleware interface {
+	process(ctx context.Context, m *chatMessage) error
+}
+
+type middlewareFunc func(ctx context.Context, m *chatMessage) error
+
+func (f middlewareFunc) process(ctx context.Context, m *chatMessage) error {
+	return f(ctx, m)
+}
+
+type namedMiddleware struct {
+	name string
+	middleware
+}
+
+// pipeline runs middlewares in order, stopping at the first error.
+type pipeline struct {
+	log         hclog.Logger
+	middlewares []namedMiddleware
+}
+
+func (p pipeline) process(ctx context.Context, m *chatMessage) error {
+	for _, mw := range p.middlewares {
+		if err := mw.process(ctx, m); err != nil {
+			requestLogger(ctx, p.log).Info("message stopped by middleware", "sender", m.sender, "middleware", mw.name, "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// middlewareFactories lists the middlewares that can be enabled by name
+// with the -middleware flag.
+var middlewareFactories = map[string]func(s *server, opts middlewareOptions) middleware{
+	"validate": func(s *server, opts middlewareOptions) middleware {
+		return messageValidator{maxLength: opts.maxMessageLength}
+	},
+	"profanity": func(s *server, opts middlewareOptions) middleware {
+		return newProfanityFilter(opts.bannedWords, opts.blockProfanity)
+	},
+	"links": func(s *server, opts middlewareOptions) middleware {
+		return middlewareFunc(detectLinks)
+	},
+	"mentions": func(s *server, opts middlewareOptions) middleware {
+		return mentionParser{s: s}
+	},
+	"scripts": func(s *server, opts middlewareOptions) middleware {
+		if opts.scripts == nil {
+			return pipeline{}
+		}
+		return opts.scripts
+	},
+}
+
+type middlewareOptions struct {
+	log              hclog.Logger
+	bannedWords      []string
+	blockProfanity   bool
+	maxMessageLength int
+	scripts          *scriptEngine
+}
+
+func buildPipeline(s *server, names []string, opts middlewareOptions) (pipeline, error) {
+	p := pipeline{log: opts.log}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		factory, ok := middlewareFactories[name]
+		if !ok {
+			return pipeline{}, fmt.Errorf("unknown middleware: %s", name)
+		}
+		p.middlewares = append(p.middlewares, namedMiddleware{name: name, middleware: factory(s, opts)})
+	}
+	return p, nil
+}
+
+// pipelineFor builds the pipeline cfg configures.
+func (s *server) pipelineFor(cfg *config) (pipeline, error) {
+	return buildPipeline(s, cfg.Features.Middleware, middlewareOptions{
+		log:              s.logs.named("middleware"),
+		bannedWords:      cfg.Features.BannedWords,
+		blockProfanity:   cfg.Features.BlockProfanity,
+		maxMessageLength: cfg.Limits.MaxMessageLength,
+		scripts:          s.scripts,
+	})
+}
+
+func setMetadata(m *chatMessage, key, value string) {
+	if m.metadata == nil {
+		m.metadata = make(map[string]string)
+	}
+	m.metadata[key] = value
+}
+
+// messageValidator checks the text as Message does. Later in the
+// pipeline it catches texts middlewares such as scripts made too long.
+type messageValidator struct {
+	maxLength int
+}
+
+func (v messageValidator) process(ctx context.Context, m *chatMessage) error {
+	return validateMessageText(m.text, v.maxLength)
+}
+
+type profanityFilter struct {
+	re    *regexp.Regexp
+	block bool
+}
+
+// newProfanityFilter masks banned words with asterisks, or vetoes the
+// message altogether if block is set.
+func newProfanityFilter(words []string, block bool) *profanityFilter {
+	var quoted []string
+	for _, w := range words {
+		if w = strings.TrimSpace(w); w != "" {
+			quoted = append(quoted, regexp.QuoteMeta(w))
+		}
+	}
+	if len(quoted) == 0 {
+		return &profanityFilter{}
+	}
+	// longest first, alternatives are tried in order
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	re := regexp.MustCompile(`(?i)(?:` + strings.Join(quoted, "|") + `)`)
+	return &profanityFilter{re: re, block: block}
+}
+
+// words returns where banned words are in text. \b only knows ASCII
+// letters, so whole words are told apart here.
+func (f *profanityFilter) words(text string) [][]int {
+	var found [][]int
+	for _, loc := range f.re.FindAllStringIndex(text, -1) {
+		before, _ := utf8.DecodeLastRuneInString(text[:loc[0]])
+		after, _ := utf8.DecodeRuneInString(text[loc[1]:])
+		if !isWordRune(before) && !isWordRune(after) {
+			found = append(found, loc)
+		}
+	}
+	return found
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (f *profanityFilter) process(ctx context.Context, m *chatMessage) error {
+	if f.re == nil {
+		return nil
+	}
+	found := f.words(m.text)
+	if len(found) == 0 {
+		return nil
+	}
+	if f.block {
+		return status.Error(codes.PermissionDenied, "message contains banned words")
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range found {
+		b.WriteString(m.text[last:loc[0]])
+		b.WriteString(strings.Repeat("*", utf8.RuneCountInString(m.text[loc[0]:loc[1]])))
+		last = loc[1]
+	}
+	b.WriteString(m.text[last:])
+	m.text = b.String()
+	return nil
+}
+
+var linkRe = regexp.MustCompile(`\bhttps?://[^\s<>"]+`)
+
+// detectLinks stores links found in the text as space separated
+// "links" metadata.
+func detectLinks(ctx context.Context, m *chatMessage) error {
+	links := linkRe.FindAllString(m.text, -1)
+	if len(links) > 0 {
+		setMetadata(m, "links", strings.Join(links, " "))
+	}
+	return nil
+}
+
+var mentionRe = regexp.MustCompile(`(?:^|\s)@([^\s@,.:;!?]+)`)
+
+// mentionParser stores the ids of connected clients mentioned as @name as
+// comma separated "mentions" metadata.
+type mentionParser struct {
+	s *server
+}
+
+func (p mentionParser) process(ctx context.Context, m *chatMessage) error {
+	matches := mentionRe.FindAllStringSubmatch(m.text, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	var ids []string
+	seen := make(map[string]bool)
+	for _, match := range matches {
+		c, err := p.s.getClientByName(match[1])
+		if err != nil || seen[c.clientId.String()] {
+			continue
+		}
+		seen[c.clientId.String()] = true
+		ids = append(ids, c.clientId.String())
+	}
+	if len(ids) > 0 {
+		setMetadata(m, "mentions", strings.Join(ids, ","))
+	}
+	return nil
+}
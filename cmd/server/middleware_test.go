@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMessageValidator(t *testing.T) {
+	v := messageValidator{maxLength: 5}
+	tests := []struct {
+		name string
+		text string
+		code codes.Code
+	}{
+		{"ok", "hello", codes.OK},
+		{"multibyte at limit", "żółwi", codes.OK},
+		{"empty", "", codes.InvalidArgument},
+		{"blank", " \t\n", codes.InvalidArgument},
+		{"too long", "hello!", codes.InvalidArgument},
+		{"invalid utf-8", "a\xffb", codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.process(context.Background(), &chatMessage{text: tt.text})
+			if code := status.Code(err); code != tt.code {
+				t.Errorf("got %s (%v), want %s", code, err, tt.code)
+			}
+		})
+	}
+}
+
+func TestProfanityFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []string
+		block bool
+		text  string
+		want  string
+		code  codes.Code
+	}{
+		{"no words", nil, false, "darn it", "darn it", codes.OK},
+		{"masked", []string{"darn"}, false, "darn it", "**** it", codes.OK},
+		{"any case", []string{"darn"}, false, "DaRn it", "**** it", codes.OK},
+		{"whole words only", []string{"darn"}, false, "darned", "darned", codes.OK},
+		{"multibyte", []string{"żółw"}, false, "a żółw", "a ****", codes.OK},
+		{"inside a multibyte word", []string{"żółw"}, false, "żółwie", "żółwie", codes.OK},
+		{"repeated", []string{"darn"}, false, "darn,darn darn", "****,**** ****", codes.OK},
+		{"longest word wins", []string{"ass", "assassin"}, false, "assassin", "********", codes.OK},
+		{"blocked", []string{"darn"}, true, "darn it", "darn it", codes.PermissionDenied},
+		{"clean while blocking", []string{"darn"}, true, "hi", "hi", codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &chatMessage{text: tt.text}
+			err := newProfanityFilter(tt.words, tt.block).process(context.Background(), m)
+			if code := status.Code(err); code != tt.code {
+				t.Errorf("got %s (%v), want %s", code, err, tt.code)
+			}
+			if m.text != tt.want {
+				t.Errorf("text = %q, want %q", m.text, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectLinks(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"no links here", ""},
+		{"see https://example.com/a?b=c", "https://example.com/a?b=c"},
+		{"http://a.io and https://b.io", "http://a.io https://b.io"},
+		{"ftp://example.com", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			m := &chatMessage{text: tt.text}
+			if err := detectLinks(context.Background(), m); err != nil {
+				t.Fatal(err)
+			}
+			if got := m.metadata["links"]; got != tt.want {
+				t.Errorf("links = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMentionParser(t *testing.T) {
+	s := newTestServer(t)
+	alice := addTestClient(s, "alice")
+	bob := addTestClient(s, "Bob")
+	p := mentionParser{s: s}
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"hi all", ""},
+		{"hi @alice", alice.clientId.String()},
+		{"@bob, @alice: and @alice again", bob.clientId.String() + "," + alice.clientId.String()},
+		{"@nobody", ""},
+		{"mail alice@example.com", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			m := &chatMessage{text: tt.text}
+			if err := p.process(context.Background(), m); err != nil {
+				t.Fatal(err)
+			}
+			if got := m.metadata["mentions"]; got != tt.want {
+				t.Errorf("mentions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScriptsMiddleware(t *testing.T) {
+	s := newTestServer(t)
+	dir := t.TempDir()
+	script := `
+def on_message(msg):
+    if "spam" in msg.text:
+        return veto("no spam")
+    if msg.metadata.get("integration"):
+        return "[" + msg.metadata["integration"] + "] " + msg.text
+    return msg.text.upper()
+`
+	if err := os.WriteFile(filepath.Join(dir, "10-rules.star"), []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := newScriptEngine(s, dir)
+	e.reload()
+
+	sender := addTestClient(s, "alice").clientId.String()
+	tests := []struct {
+		name string
+		m    chatMessage
+		want string
+		code codes.Code
+	}{
+		{"rewritten", chatMessage{sender: sender, text: "hi"}, "HI", codes.OK},
+		{"vetoed", chatMessage{sender: sender, text: "buy spam"}, "buy spam", codes.PermissionDenied},
+		{"sent by a script", chatMessage{sender: systemSender.String(), text: "hi"}, "hi", codes.OK},
+		{"incoming webhook", chatMessage{sender: systemSender.String(), text: "hi", metadata: map[string]string{"integration": "ci"}}, "[ci] hi", codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.m
+			err := e.process(context.Background(), &m)
+			if code := status.Code(err); code != tt.code {
+				t.Errorf("got %s (%v), want %s", code, err, tt.code)
+			}
+			if m.text != tt.want {
+				t.Errorf("text = %q, want %q", m.text, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildPipeline(t *testing.T) {
+	s := newTestServer(t)
+	opts := middlewareOptions{log: hclog.NewNullLogger(), bannedWords: []string{"darn"}, blockProfanity: true, maxMessageLength: 10}
+	tests := []struct {
+		name   string
+		names  []string
+		text   string
+		want   string
+		code   codes.Code
+		errMsg string
+	}{
+		{"empty", nil, "darn", "darn", codes.OK, ""},
+		{"in order", []string{"validate", "links"}, "http://a.b", "http://a.b", codes.OK, ""},
+		{"stops at first error", []string{"validate", "profanity"}, "darn it all day", "darn it all day", codes.InvalidArgument, ""},
+		{"scripts without an engine", []string{"scripts"}, "hi", "hi", codes.OK, ""},
+		{"unknown", []string{"validate", "nope"}, "", "", codes.OK, "unknown middleware: nope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := buildPipeline(s, tt.names, opts)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("got %v, want %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := &chatMessage{text: tt.text}
+			err = p.process(context.Background(), m)
+			if code := status.Code(err); code != tt.code {
+				t.Errorf("got %s (%v), want %s", code, err, tt.code)
+			}
+			if m.text != tt.want {
+				t.Errorf("text = %q, want %q", m.text, tt.want)
+			}
+		})
+	}
+}
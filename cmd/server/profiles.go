@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	// time zones are checked the same wherever the server runs
+	_ "time/tzdata"
+
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	MaxDisplayNameLength = 64
+	MaxPronounsLength    = 32
+	MaxBioLength         = 500
+	MaxStatusLength      = 100
+	// how often expired statuses are cleared
+	StatusSweep = 30 * time.Second
+)
+
+// profileFields are the fields UpdateProfile sets, by name.
+var profileFields = []string{"display_name", "pronouns", "bio", "time_zone", "avatar", "status"}
+
+// validateText checks a free text profile field, bio may span lines.
+func validateText(field, text string, limit int) error {
+	if !utf8.ValidString(text) {
+		return invalidField(field, "is not valid UTF-8")
+	}
+	if n := utf8.RuneCountInString(text); n > limit {
+		return invalidField(field, "is %d characters long, limit is %d", n, limit)
+	}
+	control := strings.IndexFunc(text, func(r rune) bool {
+		return unicode.IsControl(r) && (field != "profile.bio" || r != '\n')
+	})
+	if control >= 0 {
+		return invalidField(field, "can not contain control characters")
+	}
+	return nil
+}
+
+// validateProfile checks the fields of p UpdateProfile is asked to set.
+func validateProfile(p *pb.Profile, fields []string, now time.Time) error {
+	for _, f := range fields {
+		switch f {
+		case "display_name":
+			if err := validateText("profile.display_name", p.GetDisplayName(), MaxDisplayNameLength); err != nil {
+				return err
+			}
+		case "pronouns":
+			if err := validateText("profile.pronouns", p.GetPronouns(), MaxPronounsLength); err != nil {
+				return err
+			}
+		case "bio":
+			if err := validateText("profile.bio", p.GetBio(), MaxBioLength); err != nil {
+				return err
+			}
+		case "time_zone":
+			tz := p.GetTimeZone()
+			if tz == "" {
+				continue
+			}
+			if _, err := time.LoadLocation(tz); err != nil || tz == "Local" {
+				return invalidField("profile.time_zone", "%q is not an IANA time zone", tz)
+			}
+		case "avatar":
+			if err := validateAvatar(p.GetAvatar()); err != nil {
+				return err
+			}
+		case "status":
+			if err := validateText("profile.status", p.GetStatus(), MaxStatusLength); err != nil {
+				return err
+			}
+			expires := p.GetStatusExpiresAt()
+			switch {
+			case expires == 0:
+			case p.GetStatus() == "":
+				return invalidField("profile.status_expires_at", "is set without a status")
+			case expires <= now.Unix():
+				return invalidField("profile.status_expires_at", "is in the past")
+			}
+		default:
+			return invalidField("fields", "unknown field %q, expected one of %s", f, strings.Join(profileFields, ", "))
+		}
+	}
+	return nil
+}
+
+func validateAvatar(a *pb.Attachment) error {
+	if a == nil {
+		return nil
+	}
+	u, err := url.Parse(a.GetUrl())
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return invalidField("profile.avatar.url", "%q is not an http or https url", a.GetUrl())
+	}
+	if !strings.HasPrefix(a.GetContentType(), "image/") {
+		return invalidField("profile.avatar.content_type", "%q is not an image", a.GetContentType())
+	}
+	return nil
+}
+
+// mergeProfile returns a copy of cur with fields set from p.
+func mergeProfile(cur, p *pb.Profile, fields []string) *pb.Profile {
+	merged := &pb.Profile{}
+	if cur != nil {
+		merged = proto.Clone(cur).(*pb.Profile)
+	}
+	for _, f := range fields {
+		switch f {
+		case "display_name":
+			merged.DisplayName = p.GetDisplayName()
+		case "pronouns":
+			merged.Pronouns = p.GetPronouns()
+		case "bio":
+			merged.Bio = p.GetBio()
+		case "time_zone":
+			merged.TimeZone = p.GetTimeZone()
+		case "avatar":
+			merged.Avatar = p.GetAvatar()
+		case "status":
+			merged.Status, merged.StatusExpiresAt = p.GetStatus(), p.GetStatusExpiresAt()
+		}
+	}
+	return merged
+}
+
+func statusExpired(p *pb.Profile, now time.Time) bool {
+	return p.GetStatusExpiresAt() != 0 && p.GetStatusExpiresAt() <= now.Unix()
+}
+
+// visibleProfile is p without its status once that expired, before the
+// sweep got to it.
+func visibleProfile(p *pb.Profile, now time.Time) *pb.Profile {
+	if !statusExpired(p, now) {
+		return p
+	}
+	visible := proto.Clone(p).(*pb.Profile)
+	visible.Status, visible.StatusExpiresAt = "", 0
+	return visible
+}
+
+// connectedClient is how c is shown to other clients.
+func connectedClient(c *client) *pb.ConnectedClientsResponse_ConnectedClient {
+	return &pb.ConnectedClientsResponse_ConnectedClient{
+		Name:    c.name,
+		Id:      c.clientId.String(),
+		Profile: visibleProfile(c.profile, time.Now()),
+	}
+}
+
+func (s *server) GetProfile(ctx context.Context, in *pb.GetProfileRequest) (*pb.GetProfileResponse, error) {
+	id, err := parseID("client_id", in.GetClientId())
+	if err != nil {
+		return nil, err
+	}
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	c, err := getClientById(id, s.clients)
+	if err != nil {
+		return nil, err
+	}
+	p := visibleProfile(c.profile, time.Now())
+	if p == nil {
+		p = &pb.Profile{}
+	}
+	return &pb.GetProfileResponse{Profile: p}, nil
+}
+
+func (s *server) UpdateProfile(ctx context.Context, in *pb.UpdateProfileRequest) (*pb.UpdateProfileResponse, error) {
+	id, err := parseID("client_id", in.GetClientId())
+	if err != nil {
+		return nil, err
+	}
+	fields := in.GetFields()
+	if len(fields) == 0 {
+		fields = profileFields
+	}
+	if err := validateProfile(in.GetProfile(), fields, time.Now()); err != nil {
+		return nil, err
+	}
+	s.clientsMu.Lock()
+	c, err := getClientById(id, s.clients)
+	s.clientsMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if c.peer != nil {
+		return nil, permissionDenied("REMOTE_USER", "can not change the profile of a user of another server")
+	}
+	p := s.updateProfile(c, func(cur *pb.Profile) *pb.Profile {
+		return mergeProfile(cur, in.GetProfile(), fields)
+	})
+	requestLogger(ctx, s.log).Info("profile updated", "fields", fields)
+	return &pb.UpdateProfileResponse{Profile: p}, nil
+}
+
+// updateProfile replaces the profile of c with what update makes of it
+// and tells everyone. Profiles are never modified in place, copies of c
+// share them.
+func (s *server) updateProfile(c *client, update func(cur *pb.Profile) *pb.Profile) *pb.Profile {
+	s.clientsMu.Lock()
+	p := update(c.profile)
+	if proto.Equal(p, c.profile) {
+		s.clientsMu.Unlock()
+		return p
+	}
+	c.profile = p
+	s.broadcastPresence(c, pb.PresenceEvent_UPDATED)
+	updated := *c
+	s.clientsMu.Unlock()
+	s.publishClient(updated, true)
+	s.emit(serverEvent{kind: profileUpdated, client: updated})
+	return p
+}
+
+// expireStatuses clears the statuses of local clients once they expire,
+// until ctx is done.
+func (s *server) expireStatuses(ctx context.Context) {
+	ticker := time.NewTicker(StatusSweep)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.clientsMu.Lock()
+			var expired []*client
+			for _, c := range s.clients {
+				if s.isLocal(c) && statusExpired(c.profile, now) {
+					expired = append(expired, c)
+				}
+			}
+			s.clientsMu.Unlock()
+			for _, c := range expired {
+				s.updateProfile(c, func(cur *pb.Profile) *pb.Profile {
+					return visibleProfile(cur, now)
+				})
+			}
+		}
+	}
+}
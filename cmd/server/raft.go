@@ -0,0 +1,653 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	RaftApplyTimeout      = 5 * time.Second
+	RaftSnapshotThreshold = 1024
+	RaftSnapshotRetain    = 2
+	RaftJoinBackoff       = time.Second
+	RaftJoinMaxBackoff    = 30 * time.Second
+	RaftReapInterval      = 5 * time.Second
+	// clients of an unreachable replica are kept this long so they can
+	// resume on another one
+	RaftClientTimeout = 30 * time.Second
+)
+
+// set on proposals a follower forwarded to the leader, they are not
+// forwarded again
+const raftForwardedKey = "gochat-raft-forwarded"
+
+type raftConfig struct {
+	dir       string
+	addr      string
+	grpcAddr  string
+	bootstrap bool
+	secret    string
+
+	snapshotRetain int
+	clientTimeout  time.Duration
+}
+
+// replicator keeps the clients, rooms and room memberships of the cluster
+// in a raft log, so they survive losing a replica. Replicas propose their
+// changes to the leader instead of publishing them on topicCluster and
+// apply everyone else's as the log is committed. Messages still go through
+// the broker.
+//
+// Clients of a lost replica are kept for clientTimeout, rooms included,
+// so they can resume on another replica. Like everywhere else a room only
+// lives as long as it has members: one whose members all stay away is
+// dropped when they are reaped.
+type replicator struct {
+	pb.UnimplementedRaftServer
+	s      *server
+	log    hclog.Logger
+	self   *pb.ClusterNode
+	secret string
+	// clients of replicas unreachable this long are removed
+	clientTimeout time.Duration
+	raft          *raft.Raft
+	fsm           *raftFSM
+
+	mu sync.Mutex
+	// replicas the leader failed to reach, since when
+	failing map[string]time.Time
+	// connections to other replicas by grpc address
+	conns map[string]*grpc.ClientConn
+}
+
+func newReplicator(s *server, cfg raftConfig) (*replicator, error) {
+	if err := os.MkdirAll(cfg.dir, 0o700); err != nil {
+		return nil, err
+	}
+	advertise, err := net.ResolveTCPAddr("tcp", cfg.addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid raft address %s: %v", cfg.addr, err)
+	}
+	if advertise.IP == nil || advertise.IP.IsUnspecified() {
+		return nil, fmt.Errorf("raft address %s must name a host other replicas can reach", cfg.addr)
+	}
+
+	conf := raft.DefaultConfig()
+	conf.LocalID = raft.ServerID(s.node)
+	conf.SnapshotThreshold = RaftSnapshotThreshold
+	logger := s.logs.named("raft")
+	conf.Logger = logger
+
+	store, err := raftboltdb.NewBoltStore(filepath.Join(cfg.dir, "raft.db"))
+	if err != nil {
+		return nil, fmt.Errorf("could not open raft log: %v", err)
+	}
+	snapshots, err := raft.NewFileSnapshotStoreWithLogger(cfg.dir, cfg.snapshotRetain, logger)
+	if err != nil {
+		return nil, fmt.Errorf("could not open raft snapshots: %v", err)
+	}
+	transport, err := raft.NewTCPTransportWithLogger(cfg.addr, advertise, 3, 10*time.Second, logger)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen for raft: %v", err)
+	}
+
+	r := &replicator{
+		s:             s,
+		log:           logger,
+		self:          &pb.ClusterNode{Id: s.node, RaftAddr: string(transport.LocalAddr()), GrpcAddr: cfg.grpcAddr},
+		secret:        cfg.secret,
+		clientTimeout: cfg.clientTimeout,
+		fsm:           &raftFSM{s: s, log: logger, state: newClusterState()},
+		failing:       make(map[string]time.Time),
+		conns:         make(map[string]*grpc.ClientConn),
+	}
+	r.raft, err = raft.NewRaft(conf, r.fsm, store, store, snapshots, transport)
+	if err != nil {
+		return nil, fmt.Errorf("could not start raft: %v", err)
+	}
+
+	if cfg.bootstrap {
+		existing, err := raft.HasExistingState(store, store, snapshots)
+		if err != nil {
+			return nil, err
+		}
+		if !existing {
+			err := r.raft.BootstrapCluster(raft.Configuration{Servers: []raft.Server{{
+				ID:      conf.LocalID,
+				Address: transport.LocalAddr(),
+			}}}).Error()
+			if err != nil {
+				return nil, fmt.Errorf("could not bootstrap raft cluster: %v", err)
+			}
+		}
+	}
+	return r, nil
+}
+
+func (r *replicator) run(ctx context.Context, join string) {
+	observations := make(chan raft.Observation, 100)
+	r.raft.RegisterObserver(raft.NewObserver(observations, false, nil))
+	go r.observe(ctx, observations)
+	go r.lead(ctx)
+	go r.reap(ctx)
+	if join != "" {
+		go r.join(ctx, join)
+	}
+}
+
+// observe tracks which replicas the leader can not reach.
+func (r *replicator) observe(ctx context.Context, observations <-chan raft.Observation) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case o := <-observations:
+			r.mu.Lock()
+			switch d := o.Data.(type) {
+			case raft.FailedHeartbeatObservation:
+				if _, ok := r.failing[string(d.PeerID)]; !ok {
+					since := d.LastContact
+					if since.IsZero() {
+						since = time.Now()
+					}
+					r.failing[string(d.PeerID)] = since
+				}
+			case raft.ResumedHeartbeatObservation:
+				delete(r.failing, string(d.PeerID))
+			case raft.LeaderObservation:
+				r.failing = make(map[string]time.Time)
+			}
+			r.mu.Unlock()
+		}
+	}
+}
+
+// lead records this replica's address when it becomes the leader, so
+// followers can forward to it. That is how a bootstrapped replica gets in.
+func (r *replicator) lead(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case leader := <-r.raft.LeaderCh():
+			if !leader {
+				continue
+			}
+			r.log.Info("this node is the leader", "node", r.self.GetId())
+			if known := r.fsm.node(r.self.GetId()); known != nil && proto.Equal(known, r.self) {
+				continue
+			}
+			if err := r.apply(&pb.BrokerEnvelope{Payload: &pb.BrokerEnvelope_Member{Member: r.self}}); err != nil {
+				r.log.Error("could not record leader address", "error", err)
+			}
+		}
+	}
+}
+
+// join asks the replica at addr to add this one until it succeeds.
+func (r *replicator) join(ctx context.Context, addr string) {
+	backoff := RaftJoinBackoff
+	for {
+		api, err := r.dial(addr)
+		if err == nil {
+			_, err = api.Join(r.outgoingContext(ctx), &pb.JoinRequest{Node: r.self})
+		}
+		if err == nil {
+			r.log.Info("joined cluster", "through", addr)
+			return
+		}
+		r.log.Warn("could not join cluster, retrying", "through", addr, "error", err, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > RaftJoinMaxBackoff {
+			backoff = RaftJoinMaxBackoff
+		}
+	}
+}
+
+// reap removes clients nobody holds a stream for: ones of an earlier run of
+// this replica, and on the leader ones of replicas that left or stayed
+// unreachable for clientTimeout.
+func (r *replicator) reap(ctx context.Context) {
+	ticker := time.NewTicker(RaftReapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var members map[string]bool
+		if r.raft.State() == raft.Leader {
+			future := r.raft.GetConfiguration()
+			if err := future.Error(); err == nil {
+				members = make(map[string]bool)
+				for _, srv := range future.Configuration().Servers {
+					members[string(srv.ID)] = true
+				}
+			}
+		}
+		r.mu.Lock()
+		unreachable := make(map[string]bool)
+		for node, since := range r.failing {
+			if time.Since(since) > r.clientTimeout {
+				unreachable[node] = true
+			}
+		}
+		r.mu.Unlock()
+
+		for _, cc := range r.fsm.clients() {
+			node := cc.GetNode()
+			var stale bool
+			if node == r.s.node {
+				id, _ := uuid.Parse(cc.GetId())
+				r.s.clientsMu.Lock()
+				_, held := r.s.clients[id]
+				r.s.clientsMu.Unlock()
+				stale = !held
+			} else if members != nil {
+				stale = !members[node] || unreachable[node]
+			}
+			if !stale {
+				continue
+			}
+			// proposed on behalf of the client's replica so every replica,
+			// this one included, applies it
+			gone := &pb.ClusterClient{Id: cc.GetId(), Name: cc.GetName(), Node: node}
+			if err := r.propose(ctx, &pb.BrokerEnvelope{Node: node, Payload: &pb.BrokerEnvelope_Client{Client: gone}}); err != nil {
+				r.log.Error("could not remove stale client", "client_id", cc.GetId(), "name", cc.GetName(), "error", err)
+			}
+		}
+	}
+}
+
+func (r *replicator) dial(addr string) (pb.RaftClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conn, ok := r.conns[addr]
+	if !ok {
+		var err error
+		conn, err = grpc.Dial(addr, r.s.dialCreds)
+		if err != nil {
+			return nil, err
+		}
+		r.conns[addr] = conn
+	}
+	return pb.NewRaftClient(conn), nil
+}
+
+func (r *replicator) outgoingContext(ctx context.Context) context.Context {
+	if r.secret == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+r.secret)
+}
+
+// leader returns the client of the leader, for a request that can only be
+// served there. Requests are forwarded once.
+func (r *replicator) leader(ctx context.Context) (pb.RaftClient, context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md.Get(raftForwardedKey)) > 0 {
+		return nil, nil, status.Errorf(codes.Unavailable, "not the raft leader")
+	}
+	_, id := r.raft.LeaderWithID()
+	if id == "" {
+		return nil, nil, status.Errorf(codes.Unavailable, "no raft leader")
+	}
+	node := r.fsm.node(string(id))
+	if node == nil {
+		return nil, nil, status.Errorf(codes.Unavailable, "address of raft leader %s unknown", id)
+	}
+	api, err := r.dial(node.GetGrpcAddr())
+	if err != nil {
+		return nil, nil, status.Errorf(codes.Unavailable, "could not dial raft leader: %v", err)
+	}
+	ctx = metadata.AppendToOutgoingContext(r.outgoingContext(ctx), raftForwardedKey, r.self.GetId())
+	ctx = outgoingTraceContext(ctx)
+	return api, ctx, nil
+}
+
+// propose appends env to the log through the leader and waits for it to be
+// committed.
+func (r *replicator) propose(ctx context.Context, env *pb.BrokerEnvelope) (err error) {
+	defer observeStore("raft", "propose", time.Now())
+	ctx, span := tracer.Start(ctx, "raft propose")
+	defer func() { endSpan(span, err) }()
+	if r.raft.State() == raft.Leader {
+		return r.apply(env)
+	}
+	api, ctx, err := r.leader(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, RaftApplyTimeout)
+	defer cancel()
+	_, err = api.Propose(ctx, env)
+	return err
+}
+
+func (r *replicator) apply(env *pb.BrokerEnvelope) error {
+	b, err := proto.Marshal(env)
+	if err != nil {
+		return status.Errorf(codes.Internal, "could not encode raft entry: %v", err)
+	}
+	if err := r.raft.Apply(b, RaftApplyTimeout).Error(); err != nil {
+		return status.Errorf(codes.Unavailable, "could not apply raft entry: %v", err)
+	}
+	return nil
+}
+
+// Join adds a replica to the cluster as a voter.
+func (r *replicator) Join(ctx context.Context, in *pb.JoinRequest) (*pb.JoinResponse, error) {
+	if err := authorizeReplica(ctx, r.secret); err != nil {
+		return nil, err
+	}
+	n := in.GetNode()
+	if n.GetId() == "" || n.GetRaftAddr() == "" || n.GetGrpcAddr() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "node id, raft and grpc addresses are required")
+	}
+	if r.raft.State() != raft.Leader {
+		api, ctx, err := r.leader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return api.Join(ctx, in)
+	}
+	err := r.raft.AddVoter(raft.ServerID(n.GetId()), raft.ServerAddress(n.GetRaftAddr()), 0, RaftApplyTimeout).Error()
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "could not add %s: %v", n.GetId(), err)
+	}
+	if err := r.apply(&pb.BrokerEnvelope{Payload: &pb.BrokerEnvelope_Member{Member: n}}); err != nil {
+		return nil, err
+	}
+	requestLogger(ctx, r.log).Info("node joined the cluster", "node", n.GetId(), "raft_addr", n.GetRaftAddr())
+	return &pb.JoinResponse{}, nil
+}
+
+// Leave removes a replica from the cluster, its clients are dropped.
+func (r *replicator) Leave(ctx context.Context, in *pb.LeaveRequest) (*pb.LeaveResponse, error) {
+	if err := authorizeReplica(ctx, r.secret); err != nil {
+		return nil, err
+	}
+	if in.GetId() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "node id is required")
+	}
+	if r.raft.State() != raft.Leader {
+		api, ctx, err := r.leader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return api.Leave(ctx, in)
+	}
+	// record it first, the leader may be the one leaving
+	err := r.apply(&pb.BrokerEnvelope{Payload: &pb.BrokerEnvelope_NodeEvent{
+		NodeEvent: &pb.NodeEvent{Kind: pb.NodeEvent_DOWN, Node: in.GetId()},
+	}})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.raft.RemoveServer(raft.ServerID(in.GetId()), 0, RaftApplyTimeout).Error(); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "could not remove %s: %v", in.GetId(), err)
+	}
+	requestLogger(ctx, r.log).Info("node left the cluster", "node", in.GetId())
+	return &pb.LeaveResponse{}, nil
+}
+
+func (r *replicator) Propose(ctx context.Context, in *pb.BrokerEnvelope) (*pb.ProposeResponse, error) {
+	if err := authorizeReplica(ctx, r.secret); err != nil {
+		return nil, err
+	}
+	switch in.GetPayload().(type) {
+	case *pb.BrokerEnvelope_Client, *pb.BrokerEnvelope_Room:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "only client and room changes can be proposed")
+	}
+	if r.raft.State() != raft.Leader {
+		api, ctx, err := r.leader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return api.Propose(ctx, in)
+	}
+	if err := r.apply(in); err != nil {
+		return nil, err
+	}
+	return &pb.ProposeResponse{}, nil
+}
+
+// raftFSM applies committed entries to the replicated state and, for
+// changes made on other replicas, to the server's clients and rooms.
+type raftFSM struct {
+	s   *server
+	log hclog.Logger
+
+	mu    sync.Mutex
+	state *clusterState
+}
+
+func (f *raftFSM) Apply(l *raft.Log) interface{} {
+	var env pb.BrokerEnvelope
+	if err := proto.Unmarshal(l.Data, &env); err != nil {
+		f.log.Error("invalid log entry", "index", l.Index, "error", err)
+		return nil
+	}
+	f.mu.Lock()
+	f.state.apply(&env)
+	f.mu.Unlock()
+
+	switch p := env.GetPayload().(type) {
+	case *pb.BrokerEnvelope_Client:
+		// the proposing replica already made its own changes
+		if env.GetNode() != f.s.node {
+			f.s.applyClusterClient(p.Client)
+		}
+	case *pb.BrokerEnvelope_Room:
+		if env.GetNode() != f.s.node {
+			f.s.applyRoomUpdate(p.Room)
+		}
+	case *pb.BrokerEnvelope_NodeEvent:
+		if ev := p.NodeEvent; ev.GetKind() == pb.NodeEvent_DOWN && ev.GetNode() != f.s.node {
+			f.s.dropNode(ev.GetNode())
+		}
+	}
+	return nil
+}
+
+func (f *raftFSM) Snapshot() (raft.FSMSnapshot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b, err := proto.Marshal(f.state.proto())
+	if err != nil {
+		return nil, err
+	}
+	return raftSnapshot(b), nil
+}
+
+func (f *raftFSM) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+	var st pb.ClusterState
+	if err := proto.Unmarshal(b, &st); err != nil {
+		return fmt.Errorf("invalid raft snapshot: %v", err)
+	}
+	f.mu.Lock()
+	f.state = clusterStateFromProto(&st)
+	f.mu.Unlock()
+	f.s.restoreCluster(&st)
+	return nil
+}
+
+func (f *raftFSM) node(id string) *pb.ClusterNode {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.state.nodes[id]
+}
+
+func (f *raftFSM) clients() []*pb.ClusterClient {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clients := make([]*pb.ClusterClient, 0, len(f.state.clients))
+	for _, cc := range f.state.clients {
+		clients = append(clients, cc)
+	}
+	return clients
+}
+
+type raftSnapshot []byte
+
+func (s raftSnapshot) Persist(sink raft.SnapshotSink) error {
+	if _, err := sink.Write(s); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s raftSnapshot) Release() {}
+
+// clusterState is the metadata replicated by raft, rooms map to their
+// topic and members.
+type clusterState struct {
+	clients map[string]*pb.ClusterClient
+	rooms   map[string]*pb.RoomState
+	nodes   map[string]*pb.ClusterNode
+}
+
+func newClusterState() *clusterState {
+	return &clusterState{
+		clients: make(map[string]*pb.ClusterClient),
+		rooms:   make(map[string]*pb.RoomState),
+		nodes:   make(map[string]*pb.ClusterNode),
+	}
+}
+
+func clusterStateFromProto(st *pb.ClusterState) *clusterState {
+	cs := newClusterState()
+	for _, cc := range st.GetClients() {
+		cs.clients[cc.GetId()] = cc
+	}
+	for _, rs := range st.GetRooms() {
+		cs.rooms[rs.GetName()] = rs
+	}
+	for _, n := range st.GetNodes() {
+		cs.nodes[n.GetId()] = n
+	}
+	return cs
+}
+
+func (cs *clusterState) proto() *pb.ClusterState {
+	st := &pb.ClusterState{}
+	for _, cc := range cs.clients {
+		st.Clients = append(st.Clients, cc)
+	}
+	for _, rs := range cs.rooms {
+		st.Rooms = append(st.Rooms, rs)
+	}
+	for _, n := range cs.nodes {
+		st.Nodes = append(st.Nodes, n)
+	}
+	return st
+}
+
+// apply mirrors applyClusterClient and applyRoomUpdate.
+func (cs *clusterState) apply(env *pb.BrokerEnvelope) {
+	switch p := env.GetPayload().(type) {
+	case *pb.BrokerEnvelope_Client:
+		cc := p.Client
+		if cc.GetOnline() {
+			cs.clients[cc.GetId()] = cc
+		} else if known, ok := cs.clients[cc.GetId()]; ok && known.GetNode() == cc.GetNode() {
+			cs.removeClient(cc.GetId())
+		}
+	case *pb.BrokerEnvelope_Room:
+		ru := p.Room
+		rs, ok := cs.rooms[ru.GetRoom()]
+		switch ru.GetChange() {
+		case pb.RoomUpdate_JOINED:
+			if _, known := cs.clients[ru.GetClientId()]; !known {
+				return
+			}
+			if !ok {
+				rs = &pb.RoomState{Name: ru.GetRoom()}
+				cs.rooms[ru.GetRoom()] = rs
+			}
+			if !containsString(rs.MemberIds, ru.GetClientId()) {
+				rs.MemberIds = append(rs.MemberIds, ru.GetClientId())
+			}
+			if ru.GetTopic() != "" {
+				rs.Topic = ru.GetTopic()
+			}
+		case pb.RoomUpdate_LEFT:
+			if ok {
+				cs.removeMember(rs, ru.GetClientId())
+			}
+		case pb.RoomUpdate_TOPIC:
+			if ok {
+				rs.Topic = ru.GetTopic()
+			}
+		}
+	case *pb.BrokerEnvelope_Member:
+		cs.nodes[p.Member.GetId()] = p.Member
+	case *pb.BrokerEnvelope_NodeEvent:
+		if ev := p.NodeEvent; ev.GetKind() == pb.NodeEvent_DOWN {
+			delete(cs.nodes, ev.GetNode())
+			for id, cc := range cs.clients {
+				if cc.GetNode() == ev.GetNode() {
+					cs.removeClient(id)
+				}
+			}
+		}
+	}
+}
+
+func (cs *clusterState) removeClient(id string) {
+	delete(cs.clients, id)
+	for _, rs := range cs.rooms {
+		cs.removeMember(rs, id)
+	}
+}
+
+func (cs *clusterState) removeMember(rs *pb.RoomState, id string) {
+	for i, m := range rs.MemberIds {
+		if m == id {
+			rs.MemberIds = append(rs.MemberIds[:i], rs.MemberIds[i+1:]...)
+			break
+		}
+	}
+	if len(rs.MemberIds) == 0 {
+		delete(cs.rooms, rs.GetName())
+	}
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
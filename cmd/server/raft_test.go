@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+)
+
+type raftTestNode struct {
+	s    *server
+	addr string
+	// stop shuts the replica down as if it was lost
+	stop func()
+}
+
+// freeAddr returns a loopback address nothing listens on. Raft has to
+// advertise a port, so it can not be given :0.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// startRaftNode starts a replica serving raft over grpc on loopback. It
+// bootstraps the cluster when join is empty.
+func startRaftNode(t *testing.T, hub *memoryHub, node, join string, clientTimeout time.Duration) *raftTestNode {
+	t.Helper()
+	s := newTestServer(t)
+	s.node = node
+	s.dialCreds = grpc.WithTransportCredentials(insecure.NewCredentials())
+	s.broker = hub.attach(node)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.replicator, err = newReplicator(s, raftConfig{
+		dir:            t.TempDir(),
+		addr:           freeAddr(t),
+		grpcAddr:       lis.Addr().String(),
+		bootstrap:      join == "",
+		snapshotRetain: RaftSnapshotRetain,
+		clientTimeout:  clientTimeout,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	pb.RegisterRaftServer(srv, s.replicator)
+	go srv.Serve(lis)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go s.routeEnvelopes(ctx)
+	s.replicator.run(ctx, join)
+	stop := func() {
+		cancel()
+		s.replicator.raft.Shutdown().Error()
+		srv.Stop()
+	}
+	t.Cleanup(stop)
+	return &raftTestNode{s: s, addr: lis.Addr().String(), stop: stop}
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(15 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func (n *raftTestNode) hasClient(name string) bool {
+	_, err := n.s.getClientByName(name)
+	return err == nil
+}
+
+func (n *raftTestNode) roomMembers(name string) int {
+	r, err := n.s.getRoom(name)
+	if err != nil {
+		return 0
+	}
+	return len(r.members)
+}
+
+// startRaftCluster starts three replicas, n1 leading, and waits until
+// they all know each other.
+func startRaftCluster(t *testing.T, clientTimeout time.Duration) []*raftTestNode {
+	t.Helper()
+	hub := newMemoryHub(hclog.NewNullLogger())
+	n1 := startRaftNode(t, hub, "n1", "", clientTimeout)
+	eventually(t, "n1 to lead", func() bool { return n1.s.replicator.raft.State() == raft.Leader })
+	n2 := startRaftNode(t, hub, "n2", n1.addr, clientTimeout)
+	n3 := startRaftNode(t, hub, "n3", n1.addr, clientTimeout)
+	nodes := []*raftTestNode{n1, n2, n3}
+
+	eventually(t, "the replicas to join", func() bool {
+		for _, n := range nodes {
+			for _, id := range []string{"n1", "n2", "n3"} {
+				if n.s.replicator.fsm.node(id) == nil {
+					return false
+				}
+			}
+		}
+		return true
+	})
+	return nodes
+}
+
+func TestRaftReplicatesJoinAndLeave(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts a raft cluster")
+	}
+	nodes := startRaftCluster(t, RaftClientTimeout)
+	n1, n2, n3 := nodes[0], nodes[1], nodes[2]
+
+	ctx := context.Background()
+	resp, err := n2.s.Connect(ctx, &pb.ConnectRequest{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, err := n2.s.findLocalClient(resp.GetClientId())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n3.s.Connect(ctx, &pb.ConnectRequest{Name: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "alice and bob to replicate", func() bool {
+		for _, n := range nodes {
+			if !n.hasClient("alice") || !n.hasClient("bob") {
+				return false
+			}
+		}
+		return true
+	})
+
+	if _, err := n2.s.joinRoom(alice, "#general"); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "the room join to replicate", func() bool {
+		for _, n := range nodes {
+			if n.roomMembers("#general") != 1 {
+				return false
+			}
+		}
+		return true
+	})
+	if err := n2.s.leaveRoom(alice, "#general"); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "the room leave to replicate", func() bool {
+		for _, n := range nodes {
+			if n.roomMembers("#general") != 0 {
+				return false
+			}
+		}
+		return true
+	})
+
+	// n3 leaves, taking bob with it
+	if _, err := n1.s.replicator.Leave(ctx, &pb.LeaveRequest{Id: "n3"}); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "the node leave to replicate", func() bool {
+		for _, n := range []*raftTestNode{n1, n2} {
+			if n.s.replicator.fsm.node("n3") != nil || n.hasClient("bob") || !n.hasClient("alice") {
+				return false
+			}
+		}
+		return true
+	})
+	future := n1.s.replicator.raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if servers := future.Configuration().Servers; len(servers) != 2 {
+		t.Errorf("raft has %d voters after n3 left, want 2", len(servers))
+	}
+}
+
+// Clients of a lost replica are kept for clientTimeout, and with them
+// their rooms, so they can resume on another replica. Rooms whose members
+// all stay away go with them, like rooms whose members disconnect.
+func TestRaftLostReplica(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts a raft cluster")
+	}
+	nodes := startRaftCluster(t, time.Second)
+	n1, n2, n3 := nodes[0], nodes[1], nodes[2]
+
+	ctx := context.Background()
+	var bob *client
+	for _, name := range []string{"bob", "carol"} {
+		resp, err := n3.s.Connect(ctx, &pb.ConnectRequest{Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := n3.s.findLocalClient(resp.GetClientId())
+		if err != nil {
+			t.Fatal(err)
+		}
+		room := "#gone"
+		if name == "bob" {
+			bob, room = c, "#ops"
+		}
+		if _, err := n3.s.joinRoom(c, room); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := n3.s.setTopic(bob, "#ops", "deploys"); err != nil {
+		t.Fatal(err)
+	}
+	survivors := []*raftTestNode{n1, n2}
+	eventually(t, "the rooms to replicate", func() bool {
+		for _, n := range survivors {
+			r, err := n.s.getRoom("#ops")
+			if err != nil || r.topic != "deploys" || n.roomMembers("#gone") != 1 {
+				return false
+			}
+		}
+		return true
+	})
+
+	n3.stop()
+	if _, err := n2.s.subscribe(bob.clientId.String()); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "carol to be reaped", func() bool {
+		for _, n := range survivors {
+			if n.hasClient("carol") || n.roomMembers("#gone") != 0 {
+				return false
+			}
+		}
+		return true
+	})
+	for _, n := range survivors {
+		r, err := n.s.getRoom("#ops")
+		if err != nil || r.topic != "deploys" || len(r.members) != 1 {
+			t.Errorf("%s: #ops is %+v, %v after bob resumed on n2", n.s.node, r, err)
+		}
+	}
+}
+
+type memorySnapshotSink struct {
+	bytes.Buffer
+}
+
+func (s *memorySnapshotSink) ID() string    { return "memory" }
+func (s *memorySnapshotSink) Cancel() error { return nil }
+func (s *memorySnapshotSink) Close() error  { return nil }
+
+func TestRaftSnapshotRestore(t *testing.T) {
+	hub := newMemoryHub(hclog.NewNullLogger())
+	newFSM := func() *raftFSM {
+		s := newTestServer(t)
+		s.node = "n1"
+		s.broker = hub.attach(s.node)
+		return &raftFSM{s: s, log: hclog.NewNullLogger(), state: newClusterState()}
+	}
+	alice, bob := uuid.NewString(), uuid.NewString()
+	entries := []*pb.BrokerEnvelope{
+		{Payload: &pb.BrokerEnvelope_Member{Member: &pb.ClusterNode{Id: "n2", RaftAddr: "127.0.0.1:7002", GrpcAddr: "127.0.0.1:8002"}}},
+		{Node: "n2", Payload: &pb.BrokerEnvelope_Client{Client: &pb.ClusterClient{Id: alice, Name: "alice", Node: "n2", Online: true}}},
+		{Node: "n2", Payload: &pb.BrokerEnvelope_Client{Client: &pb.ClusterClient{Id: bob, Name: "bob", Node: "n2", Online: true}}},
+		{Node: "n2", Payload: &pb.BrokerEnvelope_Room{Room: &pb.RoomUpdate{Room: "#general", ClientId: alice, Change: pb.RoomUpdate_JOINED}}},
+		{Node: "n2", Payload: &pb.BrokerEnvelope_Room{Room: &pb.RoomUpdate{Room: "#general", ClientId: bob, Change: pb.RoomUpdate_JOINED}}},
+		{Node: "n2", Payload: &pb.BrokerEnvelope_Room{Room: &pb.RoomUpdate{Room: "#general", ClientId: alice, Change: pb.RoomUpdate_TOPIC, Topic: "hello"}}},
+	}
+	src := newFSM()
+	for i, env := range entries {
+		b, err := proto.Marshal(env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src.Apply(&raft.Log{Index: uint64(i + 1), Data: b})
+	}
+
+	snap, err := src.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sink memorySnapshotSink
+	if err := snap.Persist(&sink); err != nil {
+		t.Fatal(err)
+	}
+	dst := newFSM()
+	if err := dst.Restore(io.NopCloser(&sink.Buffer)); err != nil {
+		t.Fatal(err)
+	}
+
+	if !proto.Equal(dst.node("n2"), src.node("n2")) {
+		t.Errorf("node n2 restored as %v, want %v", dst.node("n2"), src.node("n2"))
+	}
+	if got := len(dst.clients()); got != 2 {
+		t.Errorf("restored %d clients, want 2", got)
+	}
+	for _, name := range []string{"alice", "bob"} {
+		if _, err := dst.s.getClientByName(name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	r, err := dst.s.getRoom("#general")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.topic != "hello" || len(r.members) != 2 {
+		t.Errorf("#general restored with topic %q and %d members, want hello and 2", r.topic, len(r.members))
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	SessionRate  = 5
+	SessionBurst = 10
+	UserRate     = 10
+	UserBurst    = 20
+	RoomRate     = 20
+	RoomBurst    = 40
+	MuteAfter    = 5
+	MuteWindow   = time.Minute
+	MuteDuration = 5 * time.Minute
+	// how often idle buckets are forgotten
+	RateLimitSweep = time.Minute
+)
+
+const (
+	// retryAfterHeader is set on the trailer of refused messages, in
+	// whole seconds like the HTTP Retry-After header.
+	retryAfterHeader = "retry-after"
+	// remoteAddrHeader is the address of the http client of a request
+	// the gateway forwards, selfTokenHeader proves this server sent it.
+	remoteAddrHeader = "x-remote-addr"
+	selfTokenHeader  = "x-self-token"
+)
+
+// tokenBucket holds tokens as of last.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(l rateLimit, now time.Time) *tokenBucket {
+	return &tokenBucket{tokens: float64(l.Burst), last: now}
+}
+
+func (b *tokenBucket) refill(l rateLimit, now time.Time) {
+	b.tokens = math.Min(float64(l.Burst), b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	b.last = now
+}
+
+// wait is how long until the bucket has a token, it must be refilled.
+func (b *tokenBucket) wait(l rateLimit) time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / l.Rate * float64(time.Second))
+}
+
+// full reports whether the bucket is as good as new, so it can be
+// forgotten.
+func (b *tokenBucket) full(l rateLimit, now time.Time) bool {
+	return l.Rate == 0 || now.Sub(b.last).Seconds()*l.Rate+b.tokens >= float64(l.Burst)
+}
+
+type bucketKey struct {
+	scope string
+	key   string
+}
+
+// strikes counts the rate limit hits of a connection.
+type strikes struct {
+	hits       []time.Time
+	mutedUntil time.Time
+}
+
+// rateLimiter refuses messages once their sender's connection, the
+// sender itself, an integration or the target room sent more than the
+// limits allow. Users are known by their id, so renaming does not reset
+// their buckets, and those who keep hitting limits are muted for a
+// while. Limits are read from the config on every message, so reload
+// applies them to existing buckets.
+type rateLimiter struct {
+	s   *server
+	log hclog.Logger
+
+	mu      sync.Mutex
+	buckets map[bucketKey]*tokenBucket
+	strikes map[string]*strikes
+}
+
+func newRateLimiter(s *server, log hclog.Logger) *rateLimiter {
+	return &rateLimiter{
+		s:       s,
+		log:     log,
+		buckets: make(map[bucketKey]*tokenBucket),
+		strikes: make(map[string]*strikes),
+	}
+}
+
+// allow takes a token from every bucket in m's way, m came in on the
+// connection conn. When one is empty nothing is taken and allow returns
+// a ResourceExhausted error with how long the sender should wait.
+func (l *rateLimiter) allow(conn string, m *pb.ChatMessage) (time.Duration, error) {
+	var user *client
+	if id, err := uuid.Parse(m.GetSenderId()); err == nil {
+		l.s.clientsMu.Lock()
+		if c, ok := l.s.clients[id]; ok {
+			copied := *c
+			user = &copied
+		}
+		l.s.clientsMu.Unlock()
+	}
+	if user == nil {
+		// unknown senders are refused by Message itself
+		return 0, nil
+	}
+	id := user.clientId.String()
+	if conn == "" {
+		conn = id
+	}
+	room := m.GetRoom()
+	if name, err := roomName(room); err == nil {
+		room = name
+	}
+	limits := l.s.config().Limits
+	checks := []limitCheck{
+		{bucketKey{"session", conn}, limits.SessionRate},
+		{bucketKey{"user", id}, limits.UserRate},
+	}
+	return l.take(id, user.name, room, checks)
+}
+
+// allowIntegration applies the limits to a message the integration name
+// posts to room, or to a user when room is empty. An integration has
+// the rate of a user and is muted like a connection.
+func (l *rateLimiter) allowIntegration(name, room string) (time.Duration, error) {
+	checks := []limitCheck{
+		{bucketKey{"integration", name}, l.s.config().Limits.UserRate},
+	}
+	return l.take("integration:"+name, name, room, checks)
+}
+
+type limitCheck struct {
+	key   bucketKey
+	limit rateLimit
+}
+
+// take takes a token from the buckets of checks and the one of room.
+// Hits count as strikes against who, which is logged as name.
+func (l *rateLimiter) take(who, name, room string, checks []limitCheck) (time.Duration, error) {
+	limits := l.s.config().Limits
+	if room != "" {
+		checks = append(checks, limitCheck{bucketKey{"room", room}, limits.RoomRate})
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if st, ok := l.strikes[who]; ok && now.Before(st.mutedUntil) {
+		rateLimited.WithLabelValues("muted").Inc()
+		wait := st.mutedUntil.Sub(now)
+		return wait, status.Errorf(codes.ResourceExhausted, "muted for sending too fast, retry in %s", roundUp(wait))
+	}
+
+	var buckets []*tokenBucket
+	for _, c := range checks {
+		if c.limit.Rate == 0 {
+			continue
+		}
+		b, ok := l.buckets[c.key]
+		if !ok {
+			b = newTokenBucket(c.limit, now)
+			l.buckets[c.key] = b
+		}
+		b.refill(c.limit, now)
+		if wait := b.wait(c.limit); wait > 0 {
+			rateLimited.WithLabelValues(c.key.scope).Inc()
+			if muted := l.strike(who, name, limits.Mute, now); muted > 0 {
+				return muted, status.Errorf(codes.ResourceExhausted, "muted for sending too fast, retry in %s", roundUp(muted))
+			}
+			return wait, status.Errorf(codes.ResourceExhausted, "%s rate limit exceeded, retry in %s", c.key.scope, roundUp(wait))
+		}
+		buckets = append(buckets, b)
+	}
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return 0, nil
+}
+
+// strike records a rate limit hit of who and returns how long it is
+// muted for when that was one too many. l.mu must be held.
+func (l *rateLimiter) strike(who, name string, mute muteSettings, now time.Time) time.Duration {
+	if mute.After == 0 {
+		return 0
+	}
+	st, ok := l.strikes[who]
+	if !ok {
+		st = &strikes{}
+		l.strikes[who] = st
+	}
+	hits := st.hits[:0]
+	for _, t := range st.hits {
+		if now.Sub(t) < mute.Window {
+			hits = append(hits, t)
+		}
+	}
+	st.hits = append(hits, now)
+	if len(st.hits) < mute.After {
+		return 0
+	}
+	st.hits = nil
+	st.mutedUntil = now.Add(mute.Duration)
+	l.log.Warn("muting sender for sending too fast", "sender", who, "name", name, "duration", mute.Duration)
+	return mute.Duration
+}
+
+// run forgets buckets that refilled and strikes that expired until ctx
+// is done.
+func (l *rateLimiter) run(ctx context.Context) {
+	ticker := time.NewTicker(RateLimitSweep)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			l.sweep(now)
+		}
+	}
+}
+
+func (l *rateLimiter) sweep(now time.Time) {
+	limits := l.s.config().Limits
+	byScope := map[string]rateLimit{
+		"session":     limits.SessionRate,
+		"user":        limits.UserRate,
+		"integration": limits.UserRate,
+		"room":        limits.RoomRate,
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, b := range l.buckets {
+		if b.full(byScope[key.scope], now) {
+			delete(l.buckets, key)
+		}
+	}
+	for session, st := range l.strikes {
+		expired := len(st.hits) == 0 || now.Sub(st.hits[len(st.hits)-1]) >= limits.Mute.Window
+		if expired && !now.Before(st.mutedUntil) {
+			delete(l.strikes, session)
+		}
+	}
+}
+
+// connection names the connection the request of ctx came in on. Requests
+// the gateway and websocket handlers forward over the self-dial are named
+// by the address of their http client instead, if they carry the token
+// of this server.
+func (l *rateLimiter) connection(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	token := md.Get(selfTokenHeader)
+	if len(token) == 1 && l.s.selfToken != "" && subtle.ConstantTimeCompare([]byte(token[0]), []byte(l.s.selfToken)) == 1 {
+		if addr := md.Get(remoteAddrHeader); len(addr) > 0 {
+			return "http:" + addr[0]
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		return "grpc:" + p.Addr.String()
+	}
+	return ""
+}
+
+// selfTokenInterceptor marks the calls of the self-dial as forwarded by
+// this server.
+func (s *server) selfTokenInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(metadata.AppendToOutgoingContext(ctx, selfTokenHeader, s.selfToken), method, req, reply, cc, opts...)
+}
+
+// unaryInterceptor applies the limits to Message calls and tells the
+// caller when to retry in the retry-after trailer.
+func (l *rateLimiter) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	m, ok := req.(*pb.ChatMessage)
+	if !ok || info.FullMethod != "/msg.ChatServer/Message" {
+		return handler(ctx, req)
+	}
+	if wait, err := l.allow(l.connection(ctx), m); err != nil {
+		grpc.SetTrailer(ctx, metadata.Pairs(retryAfterHeader, strconv.Itoa(int(roundUp(wait)/time.Second))))
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// roundUp rounds d up to whole seconds, so retrying after it succeeds.
+func roundUp(d time.Duration) time.Duration {
+	return (d + time.Second - 1).Truncate(time.Second)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func newTestLimiter(t *testing.T, limits func(l *limitSettings)) (*server, *rateLimiter) {
+	t.Helper()
+	s := newTestServer(t)
+	s.selfToken = "self"
+	limits(&s.cfg.Limits)
+	s.cfg.Limits.Mute.After = 0
+	return s, newRateLimiter(s, hclog.NewNullLogger())
+}
+
+func TestRateLimitSurvivesRename(t *testing.T) {
+	s, l := newTestLimiter(t, func(limits *limitSettings) {
+		limits.SessionRate.Rate = 0
+		limits.UserRate = rateLimit{Rate: 0.001, Burst: 2}
+	})
+	alice := addTestClient(s, "alice")
+	m := &pb.ChatMessage{SenderId: alice.clientId.String(), Text: "hi"}
+	for i := 0; i < 2; i++ {
+		if _, err := l.allow("grpc:a", m); err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+	}
+	alice.name = "carol"
+	if _, err := l.allow("grpc:b", m); err == nil {
+		t.Error("renaming and reconnecting reset the user limit")
+	}
+}
+
+func TestRateLimitSessionIsTheConnection(t *testing.T) {
+	s, l := newTestLimiter(t, func(limits *limitSettings) {
+		limits.SessionRate = rateLimit{Rate: 0.001, Burst: 2}
+		limits.UserRate.Rate = 0
+	})
+	alice, bob := addTestClient(s, "alice"), addTestClient(s, "bob")
+	for i := 0; i < 2; i++ {
+		if _, err := l.allow("grpc:a", &pb.ChatMessage{SenderId: alice.clientId.String(), Text: "hi"}); err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+	}
+	if _, err := l.allow("grpc:a", &pb.ChatMessage{SenderId: bob.clientId.String(), Text: "hi"}); err == nil {
+		t.Error("claiming another sender id escaped the session limit")
+	}
+	if _, err := l.allow("grpc:b", &pb.ChatMessage{SenderId: bob.clientId.String(), Text: "hi"}); err != nil {
+		t.Errorf("another connection: %v", err)
+	}
+}
+
+func TestRateLimitConnection(t *testing.T) {
+	_, l := newTestLimiter(t, func(*limitSettings) {})
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4000}})
+
+	tests := []struct {
+		name string
+		md   metadata.MD
+		want string
+	}{
+		{"direct", nil, "grpc:127.0.0.1:4000"},
+		{"forwarded", metadata.Pairs(selfTokenHeader, "self", remoteAddrHeader, "10.0.0.1:5000"), "http:10.0.0.1:5000"},
+		{"spoofed", metadata.Pairs(selfTokenHeader, "guess", remoteAddrHeader, "10.0.0.1:5000"), "grpc:127.0.0.1:4000"},
+		{"no token", metadata.Pairs(remoteAddrHeader, "10.0.0.1:5000"), "grpc:127.0.0.1:4000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := ctx
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+			if got := l.connection(ctx); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
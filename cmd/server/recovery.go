@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recoveryUnaryInterceptor turns a panicking handler into an Internal
+// error instead of a crashed server.
+func recoveryUnaryInterceptor(base hclog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(requestLogger(ctx, base), info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func recoveryStreamInterceptor(base hclog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(requestLogger(ss.Context(), base), info.FullMethod, r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+// recovered logs the panic with its stack, which the caller does not
+// get to see.
+func recovered(lg hclog.Logger, method string, r interface{}) error {
+	lg.Error("handler panicked", "panic", r, "stack", string(debug.Stack()))
+	rpcPanics.WithLabelValues(method).Inc()
+	return status.Errorf(codes.Internal, "internal error")
+}
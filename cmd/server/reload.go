@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// liveSettings are the settings, by YAML path, that reload applies to
+// the running server. Streams are kept open, queue limits apply to
+// clients connecting after the reload. Other settings keep their value
+// until a restart.
+var liveSettings = []string{
+	"limits",
+	"features.middleware",
+	"features.banned_words",
+	"features.block_profanity",
+	"features.webhooks",
+	"features.webhooks_dead_letter",
+	"log.level",
+	"log.levels",
+	"admin",
+	"shutdown_timeout",
+}
+
+func isLiveSetting(name string) bool {
+	for _, live := range liveSettings {
+		if name == live || strings.HasPrefix(name, live+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// config returns the configuration in effect.
+func (s *server) config() *config {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	return s.cfg
+}
+
+// reload reads the configuration again from the same file, environment
+// and flags the server was started with. It applies the live settings
+// that changed and reports them, along with the changed settings that
+// need a restart. Nothing is applied when the configuration is invalid.
+func (s *server) reload() (applied, restartRequired []string, err error) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	log := s.logs.named("reload")
+
+	next, _, err := loadConfig(os.Args[0], os.Args[1:])
+	if err == nil {
+		err = next.validate()
+	}
+	if err != nil {
+		log.Error("could not reload configuration", "error", err)
+		return nil, nil, err
+	}
+
+	cur := s.config()
+	for _, name := range changedSettings(cur, next) {
+		if isLiveSetting(name) {
+			applied = append(applied, name)
+		} else {
+			restartRequired = append(restartRequired, name)
+		}
+	}
+	cfg := *cur
+	cfg.Limits = next.Limits
+	cfg.Features.Middleware = next.Features.Middleware
+	cfg.Features.BannedWords = next.Features.BannedWords
+	cfg.Features.BlockProfanity = next.Features.BlockProfanity
+	cfg.Features.Webhooks = next.Features.Webhooks
+	cfg.Features.WebhooksDeadLetter = next.Features.WebhooksDeadLetter
+	cfg.Log.Level = next.Log.Level
+	cfg.Log.Levels = next.Log.Levels
+	cfg.Admin = next.Admin
+	cfg.ShutdownTimeout = next.ShutdownTimeout
+
+	// everything that can fail is prepared before anything is applied
+	p, err := s.pipelineFor(&cfg)
+	if err != nil {
+		log.Error("could not reload configuration", "error", err)
+		return nil, nil, err
+	}
+	var webhookConfigs []webhookConfig
+	if cfg.Features.Webhooks != "" {
+		if webhookConfigs, err = loadWebhookConfigs(cfg.Features.Webhooks); err != nil {
+			err = fmt.Errorf("invalid webhooks configuration: %v", err)
+			log.Error("could not reload configuration", "error", err)
+			return nil, nil, err
+		}
+	}
+
+	if err := s.logs.setLevels(cfg.Log); err != nil {
+		log.Error("could not reload configuration", "error", err)
+		return nil, nil, err
+	}
+	s.pipelineMu.Lock()
+	s.pipeline = p
+	s.pipelineMu.Unlock()
+	replaced := s.reloadWebhooks(webhookConfigs, cfg.Features.WebhooksDeadLetter)
+	if replaced && cfg.Features.Webhooks == cur.Features.Webhooks && cfg.Features.WebhooksDeadLetter == cur.Features.WebhooksDeadLetter {
+		// the settings are the same, the file they point to is not
+		applied = append(applied, "features.webhooks")
+	}
+	s.cfgMu.Lock()
+	s.cfg = &cfg
+	s.cfgMu.Unlock()
+
+	log.Info("reloaded configuration", "applied", applied, "restart_required", restartRequired)
+	if len(restartRequired) > 0 {
+		log.Warn("some changed settings only take effect on restart", "settings", restartRequired)
+	}
+	return applied, restartRequired, nil
+}
+
+// reloadWebhooks replaces the webhook dispatcher unless it already sends
+// to configs, and reports whether it did. Deliveries queued on the old
+// dispatcher are still made.
+func (s *server) reloadWebhooks(configs []webhookConfig, deadLetterPath string) bool {
+	s.webhooksMu.RLock()
+	cur := s.webhooks
+	s.webhooksMu.RUnlock()
+	switch {
+	case cur == nil && len(configs) == 0:
+		return false
+	case cur != nil && cur.configures(configs, deadLetterPath):
+		return false
+	case len(configs) == 0:
+		s.setWebhooks(nil)
+	default:
+		s.setWebhooks(newWebhookDispatcher(configs, deadLetterPath, s.logs.named("webhooks")))
+	}
+	return true
+}
+
+// changedSettings lists the settings, by YAML path, that differ between
+// a and b. Empty and missing lists count as equal.
+func changedSettings(a, b *config) []string {
+	var changed []string
+	var walk func(path string, a, b reflect.Value)
+	walk = func(path string, a, b reflect.Value) {
+		switch a.Kind() {
+		case reflect.Struct:
+			for i := 0; i < a.NumField(); i++ {
+				name := a.Type().Field(i).Tag.Get("yaml")
+				if path != "" {
+					name = path + "." + name
+				}
+				walk(name, a.Field(i), b.Field(i))
+			}
+			return
+		case reflect.Slice, reflect.Map:
+			if a.Len() == 0 && b.Len() == 0 {
+				return
+			}
+		}
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			changed = append(changed, path)
+		}
+	}
+	walk("", reflect.ValueOf(*a), reflect.ValueOf(*b))
+	return changed
+}
+
+// adminServer serves the Admin service to callers with the admin secret.
+type adminServer struct {
+	pb.UnimplementedAdminServer
+	s *server
+}
+
+func (a *adminServer) authorize(ctx context.Context) error {
+	secret := a.s.config().Admin.Secret
+	if secret == "" {
+		return status.Errorf(codes.PermissionDenied, "the admin service is disabled without admin.secret")
+	}
+	if !hasBearerToken(ctx, secret) {
+		return status.Errorf(codes.Unauthenticated, "invalid admin secret")
+	}
+	return nil
+}
+
+func (a *adminServer) Reload(ctx context.Context, in *pb.ReloadRequest) (*pb.ReloadResponse, error) {
+	if err := a.authorize(ctx); err != nil {
+		return nil, err
+	}
+	applied, restartRequired, err := a.s.reload()
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "could not reload: %v", err)
+	}
+	return &pb.ReloadResponse{Applied: applied, RestartRequired: restartRequired}, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+)
+
+type room struct {
+	name    string
+	topic   string
+	members map[uuid.UUID]*client
+}
+
+func (r *room) memberNames() []string {
+	names := make([]string, 0, len(r.members))
+	for _, c := range r.members {
+		names = append(names, c.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// roomName normalizes user input to the "#name" form rooms are keyed by.
+func roomName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	if name == "" || strings.ContainsAny(name, " \t\n,#") {
+		return "", invalidField("room", "%q is not a room name", name)
+	}
+	return "#" + name, nil
+}
+
+// joinRoom adds c to the room, creating it if needed. Like getRoom,
+// it returns a copy, without members.
+func (s *server) joinRoom(c *client, name string) (room, error) {
+	name, err := roomName(name)
+	if err != nil {
+		return room{}, err
+	}
+	s.clientsMu.Lock()
+	r, ok := s.rooms[name]
+	if !ok {
+		r = &room{name: name, members: make(map[uuid.UUID]*client)}
+		s.rooms[name] = r
+	}
+	r.members[c.clientId] = c
+	joined, who := room{name: r.name, topic: r.topic}, *c
+	s.clientsMu.Unlock()
+	s.publishRoomUpdate(joined.name, who.clientId, pb.RoomUpdate_JOINED, joined.topic)
+	s.syncRoomSubscription(joined.name)
+
+	s.emit(serverEvent{kind: roomJoined, client: who, room: joined.name, topic: joined.topic})
+	return joined, nil
+}
+
+// leaveRoom removes c from the room, dropping the room once it is empty.
+func (s *server) leaveRoom(c *client, name string) error {
+	name, err := roomName(name)
+	if err != nil {
+		return err
+	}
+	s.clientsMu.Lock()
+	r, ok := s.rooms[name]
+	if !ok {
+		s.clientsMu.Unlock()
+		return notFound("room", name)
+	}
+	if _, ok := r.members[c.clientId]; !ok {
+		s.clientsMu.Unlock()
+		return permissionDenied("NOT_A_MEMBER", "not a member of %s", name)
+	}
+	s.removeMember(r, c)
+	who := *c
+	s.clientsMu.Unlock()
+	s.publishRoomUpdate(name, who.clientId, pb.RoomUpdate_LEFT, "")
+	s.syncRoomSubscription(name)
+
+	s.emit(serverEvent{kind: roomLeft, client: who, room: name})
+	return nil
+}
+
+// removeMember must be called with clientsMu held.
+func (s *server) removeMember(r *room, c *client) {
+	delete(r.members, c.clientId)
+	if len(r.members) == 0 {
+		delete(s.rooms, r.name)
+	}
+}
+
+// getRoom returns a copy of the room so it can be read without clientsMu,
+// its members are copies too.
+func (s *server) getRoom(name string) (room, error) {
+	name, err := roomName(name)
+	if err != nil {
+		return room{}, err
+	}
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	r, ok := s.rooms[name]
+	if !ok {
+		return room{}, notFound("room", name)
+	}
+	members := make(map[uuid.UUID]*client, len(r.members))
+	for id, c := range r.members {
+		member := *c
+		members[id] = &member
+	}
+	return room{name: r.name, topic: r.topic, members: members}, nil
+}
+
+func (s *server) setTopic(c *client, name, topic string) error {
+	name, err := roomName(name)
+	if err != nil {
+		return err
+	}
+	s.clientsMu.Lock()
+	r, ok := s.rooms[name]
+	if !ok {
+		s.clientsMu.Unlock()
+		return notFound("room", name)
+	}
+	if _, ok := r.members[c.clientId]; !ok {
+		s.clientsMu.Unlock()
+		return permissionDenied("NOT_A_MEMBER", "not a member of %s", name)
+	}
+	r.topic = topic
+	who := *c
+	s.clientsMu.Unlock()
+	s.publishRoomUpdate(name, who.clientId, pb.RoomUpdate_TOPIC, topic)
+
+	s.emit(serverEvent{kind: roomTopicChanged, client: who, room: name, topic: topic})
+	return nil
+}
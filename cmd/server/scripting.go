@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	ScriptPollInterval = 2 * time.Second
+	// limits for a single hook invocation
+	ScriptMaxSteps = 1000000
+	ScriptTimeout  = time.Second
+)
+
+var scriptHooks = []string{"on_message", "on_connect", "on_disconnect"}
+
+type script struct {
+	name    string
+	log     hclog.Logger
+	modTime time.Time
+	hooks   map[string]starlark.Callable
+	// stop cancels the script's timers
+	stop context.CancelFunc
+}
+
+type scriptTimer struct {
+	every time.Duration
+	fn    starlark.Callable
+}
+
+// scriptEngine runs the *.star files found in dir. Scripts are sandboxed:
+// they can not load other files and only see the chat module, veto and
+// every builtins. Changed files are picked up without a restart.
+type scriptEngine struct {
+	s   *server
+	log hclog.Logger
+	dir string
+
+	mu      sync.RWMutex
+	scripts map[string]*script
+	// modification times of files that failed to load
+	failed map[string]time.Time
+}
+
+func newScriptEngine(s *server, dir string) *scriptEngine {
+	return &scriptEngine{s: s, log: s.logs.named("scripts"), dir: dir, scripts: make(map[string]*script), failed: make(map[string]time.Time)}
+}
+
+// run loads scripts and reloads them on change until ctx is done.
+func (e *scriptEngine) run(ctx context.Context) {
+	t := time.NewTicker(ScriptPollInterval)
+	defer t.Stop()
+	for {
+		e.reload()
+		select {
+		case <-ctx.Done():
+			e.mu.Lock()
+			for _, sc := range e.scripts {
+				sc.stop()
+			}
+			e.mu.Unlock()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (e *scriptEngine) reload() {
+	entries, err := os.ReadDir(e.dir)
+	if err != nil {
+		e.log.Error("could not read scripts dir", "error", err)
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".star" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		name := entry.Name()
+		seen[name] = true
+
+		e.mu.RLock()
+		old, ok := e.scripts[name]
+		e.mu.RUnlock()
+		if ok && old.modTime.Equal(info.ModTime()) {
+			continue
+		}
+		if failed, ok := e.failed[name]; ok && failed.Equal(info.ModTime()) {
+			continue
+		}
+
+		sc, err := e.load(name, info.ModTime())
+		if err != nil {
+			// keep running the previous version, if any
+			e.log.Error("could not load script", "script", name, "error", err)
+			e.failed[name] = info.ModTime()
+			continue
+		}
+		delete(e.failed, name)
+		e.mu.Lock()
+		e.scripts[name] = sc
+		e.mu.Unlock()
+		if ok {
+			old.stop()
+			e.log.Info("reloaded script", "script", name)
+		} else {
+			e.log.Info("loaded script", "script", name)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for name, sc := range e.scripts {
+		if !seen[name] {
+			sc.stop()
+			delete(e.scripts, name)
+			e.log.Info("unloaded script", "script", name)
+		}
+	}
+}
+
+func (e *scriptEngine) load(name string, modTime time.Time) (*script, error) {
+	var timers []scriptTimer
+	every := starlark.NewBuiltin("every", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var seconds starlark.Value
+		var fn starlark.Callable
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs, "seconds", &seconds, "fn", &fn); err != nil {
+			return nil, err
+		}
+		f, ok := starlark.AsFloat(seconds)
+		if !ok || f < 1 {
+			return nil, fmt.Errorf("%s: seconds must be a number >= 1", b.Name())
+		}
+		timers = append(timers, scriptTimer{every: time.Duration(f * float64(time.Second)), fn: fn})
+		return starlark.None, nil
+	})
+	predeclared := starlark.StringDict{
+		"chat":  e.chatModule(),
+		"veto":  starlark.NewBuiltin("veto", vetoBuiltin),
+		"every": every,
+	}
+
+	log := e.log.With("script", name)
+	thread, done := newScriptThread(log)
+	defer done()
+	globals, err := starlark.ExecFile(thread, filepath.Join(e.dir, name), nil, predeclared)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := &script{name: name, log: log, modTime: modTime, hooks: make(map[string]starlark.Callable)}
+	for _, hook := range scriptHooks {
+		v, ok := globals[hook]
+		if !ok {
+			continue
+		}
+		fn, ok := v.(starlark.Callable)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a function, not %s", hook, v.Type())
+		}
+		sc.hooks[hook] = fn
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sc.stop = cancel
+	for _, t := range timers {
+		go e.runTimer(ctx, sc, t)
+	}
+	return sc, nil
+}
+
+func (e *scriptEngine) runTimer(ctx context.Context, sc *script, t scriptTimer) {
+	ticker := time.NewTicker(t.every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := callScript(sc, t.fn); err != nil {
+				e.log.Error("timer failed", "script", sc.name, "error", err)
+			}
+		}
+	}
+}
+
+// newScriptThread sends what the script prints to log.
+func newScriptThread(log hclog.Logger) (*starlark.Thread, func()) {
+	thread := &starlark.Thread{
+		Print: func(t *starlark.Thread, msg string) {
+			log.Info(msg)
+		},
+	}
+	thread.SetMaxExecutionSteps(ScriptMaxSteps)
+	timer := time.AfterFunc(ScriptTimeout, func() { thread.Cancel("timeout") })
+	return thread, func() { timer.Stop() }
+}
+
+func callScript(sc *script, fn starlark.Callable, args ...starlark.Value) (starlark.Value, error) {
+	thread, done := newScriptThread(sc.log)
+	defer done()
+	return starlark.Call(thread, fn, args, nil)
+}
+
+// sorted returns loaded scripts ordered by file name, hooks run in that order.
+func (e *scriptEngine) sorted() []*script {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	scripts := make([]*script, 0, len(e.scripts))
+	for _, sc := range e.scripts {
+		scripts = append(scripts, sc)
+	}
+	sort.Slice(scripts, func(i, j int) bool { return scripts[i].name < scripts[j].name })
+	return scripts
+}
+
+// process runs on_message hooks as a middleware. A hook returns None to
+// pass the message on, a string to replace its text or veto(reason).
+// Messages of incoming webhooks are seen with the integration metadata
+// set and a sender without a name.
+func (e *scriptEngine) process(ctx context.Context, m *chatMessage) error {
+	if m.sender == systemSender.String() && m.metadata["integration"] == "" {
+		// do not run hooks on messages scripts sent themselves
+		return nil
+	}
+	for _, sc := range e.sorted() {
+		fn, ok := sc.hooks["on_message"]
+		if !ok {
+			continue
+		}
+		v, err := callScript(sc, fn, e.messageValue(m))
+		if err != nil {
+			requestLogger(ctx, e.log).Error("on_message failed", "script", sc.name, "error", err)
+			continue
+		}
+		switch v := v.(type) {
+		case starlark.NoneType:
+		case starlark.String:
+			m.text = string(v)
+		case *vetoValue:
+			return status.Errorf(codes.PermissionDenied, "message rejected: %s", v.reason)
+		default:
+			requestLogger(ctx, e.log).Error("on_message returned an unexpected value, expected None, string or veto", "script", sc.name, "type", v.Type())
+		}
+	}
+	return nil
+}
+
+// handleEvent runs on_connect and on_disconnect hooks.
+func (e *scriptEngine) handleEvent(ev serverEvent) {
+	var hook string
+	switch ev.kind {
+	case clientConnected:
+		hook = "on_connect"
+	case clientDisconnected:
+		hook = "on_disconnect"
+	default:
+		return
+	}
+	for _, sc := range e.sorted() {
+		fn, ok := sc.hooks[hook]
+		if !ok {
+			continue
+		}
+		if _, err := callScript(sc, fn, userValue(ev.client.clientId, ev.client.name)); err != nil {
+			e.log.Error("hook failed", "script", sc.name, "hook", hook, "error", err)
+		}
+	}
+}
+
+func userValue(id uuid.UUID, name string) starlark.Value {
+	return starlarkstruct.FromStringDict(starlark.String("user"), starlark.StringDict{
+		"id":   starlark.String(id.String()),
+		"name": starlark.String(name),
+	})
+}
+
+func (e *scriptEngine) messageValue(m *chatMessage) starlark.Value {
+	sender := starlark.Value(starlark.None)
+	if id, err := uuid.Parse(m.sender); err == nil {
+		name := ""
+		e.s.clientsMu.Lock()
+		if c, ok := e.s.clients[id]; ok {
+			name = c.name
+		}
+		e.s.clientsMu.Unlock()
+		sender = userValue(id, name)
+	}
+	room := starlark.Value(starlark.None)
+	if m.room != "" {
+		room = starlark.String(m.room)
+	}
+	recipient := starlark.Value(starlark.None)
+	if m.recipient != "" {
+		recipient = starlark.String(m.recipient)
+	}
+	metadata := starlark.NewDict(len(m.metadata))
+	for k, v := range m.metadata {
+		metadata.SetKey(starlark.String(k), starlark.String(v))
+	}
+	return starlarkstruct.FromStringDict(starlark.String("message"), starlark.StringDict{
+		"text":      starlark.String(m.text),
+		"sender":    sender,
+		"recipient": recipient,
+		"room":      room,
+		"metadata":  metadata,
+	})
+}
+
+// chatModule is the API scripts use to talk to the server.
+func (e *scriptEngine) chatModule() *starlarkstruct.Module {
+	return &starlarkstruct.Module{
+		Name: "chat",
+		Members: starlark.StringDict{
+			"send":  starlark.NewBuiltin("chat.send", e.builtinSend),
+			"user":  starlark.NewBuiltin("chat.user", e.builtinUser),
+			"users": starlark.NewBuiltin("chat.users", e.builtinUsers),
+		},
+	}
+}
+
+// chat.send(to, text) sends text as the server to a user name or #room.
+func (e *scriptEngine) builtinSend(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var to, text string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "to", &to, "text", &text); err != nil {
+		return nil, err
+	}
+	m := chatMessage{sender: systemSender.String(), text: text}
+	if strings.HasPrefix(to, "#") {
+		room, err := roomName(to)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", b.Name(), err)
+		}
+		m.room = room
+	} else {
+		c, err := e.s.getClientByName(to)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", b.Name(), err)
+		}
+		m.recipient = c.clientId.String()
+	}
+	if err := e.s.send(context.Background(), m); err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	return starlark.None, nil
+}
+
+// chat.user(name) returns the connected user with that name or None.
+func (e *scriptEngine) builtinUser(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name); err != nil {
+		return nil, err
+	}
+	c, err := e.s.getClientByName(name)
+	if err != nil {
+		return starlark.None, nil
+	}
+	e.s.clientsMu.Lock()
+	user := userValue(c.clientId, c.name)
+	e.s.clientsMu.Unlock()
+	return user, nil
+}
+
+// chat.users() lists connected users.
+func (e *scriptEngine) builtinUsers(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs); err != nil {
+		return nil, err
+	}
+	e.s.clientsMu.Lock()
+	users := make([]starlark.Value, 0, len(e.s.clients))
+	for _, c := range e.s.clients {
+		users = append(users, userValue(c.clientId, c.name))
+	}
+	e.s.clientsMu.Unlock()
+	return starlark.NewList(users), nil
+}
+
+// vetoValue is returned by veto(reason) to stop a message in on_message.
+type vetoValue struct {
+	reason string
+}
+
+func (v *vetoValue) String() string        { return fmt.Sprintf("veto(%q)", v.reason) }
+func (v *vetoValue) Type() string          { return "veto" }
+func (v *vetoValue) Freeze()               {}
+func (v *vetoValue) Truth() starlark.Bool  { return starlark.True }
+func (v *vetoValue) Hash() (uint32, error) { return starlark.String(v.reason).Hash() }
+
+func vetoBuiltin(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	reason := "vetoed by script"
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "reason?", &reason); err != nil {
+		return nil, err
+	}
+	return &vetoValue{reason: reason}, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const goingAwayReason = "server is shutting down"
+
+func errGoingAway() error {
+	return status.Errorf(codes.Unavailable, goingAwayReason)
+}
+
+// isGoingAway reports whether the server started shutting down.
+func (s *server) isGoingAway() bool {
+	select {
+	case <-s.goingAway:
+		return true
+	default:
+		return false
+	}
+}
+
+// shutdown reports the server as not serving, has every stream send
+// what is queued and a GoingAway event, and stops the listeners. Streams
+// still open after timeout are closed. Queued webhook deliveries are
+// sent within the same timeout and dead-lettered after it.
+func (s *server) shutdown(timeout time.Duration, grpcServer *grpc.Server, healthServer *health.Server, httpServer *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	healthServer.Shutdown()
+	close(s.goingAway)
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	if httpServer != nil {
+		if err := httpServer.Shutdown(ctx); err != nil {
+			s.log.Warn("http connections did not close in time", "error", err)
+		}
+	}
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.log.Warn("streams did not finish in time, closing them", "timeout", timeout)
+		grpcServer.Stop()
+		<-stopped
+	}
+
+	if webhooks := s.setWebhooks(nil); webhooks != nil {
+		webhooks.wait(ctx)
+	}
+
+	if s.replicator != nil {
+		if err := s.replicator.raft.Shutdown().Error(); err != nil {
+			s.log.Error("could not stop raft", "error", err)
+		}
+	}
+	s.broker.Close()
+}
+
+// healthService is the standard health service, except that Watch
+// streams end once the server goes away. They would hold up GracefulStop
+// until the timeout otherwise.
+type healthService struct {
+	*health.Server
+	goingAway <-chan struct{}
+}
+
+func (h healthService) Watch(in *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-h.goingAway:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return h.Server.Watch(in, watchStream{Health_WatchServer: stream, ctx: ctx})
+}
+
+type watchStream struct {
+	healthpb.Health_WatchServer
+	ctx context.Context
+}
+
+func (s watchStream) Context() context.Context {
+	return s.ctx
+}
+
+// goAway sends what is queued for c and then the GoingAway event, which
+// ends the stream.
+func (s *server) goAway(c *client, stream pb.ChatServer_ReceiveEventsServer) error {
+	for {
+		var err error
+		select {
+		case m := <-c.messageCh:
+			err = sendMessageEvent(stream, m)
+		case ev := <-c.eventCh:
+			err = stream.Send(ev)
+		default:
+			return stream.Send(&pb.Event{Event: &pb.Event_GoingAway{GoingAway: &pb.GoingAway{Reason: goingAwayReason}}})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// drainMessages is goAway for streams without events, they end with
+// Unavailable instead.
+func (s *server) drainMessages(c *client, stream pb.ChatServer_ReceiveMessagesServer) error {
+	for {
+		select {
+		case m := <-c.messageCh:
+			if err := sendMessage(stream, m); err != nil {
+				return err
+			}
+		default:
+			return errGoingAway()
+		}
+	}
+}
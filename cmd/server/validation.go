@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const MaxNameLength = 32
+
+// errorDomain is the domain of the ErrorInfo details of PermissionDenied
+// errors.
+const errorDomain = "gochat"
+
+// invalidField returns an InvalidArgument error with a BadRequest
+// detail naming the field.
+func invalidField(field, format string, args ...interface{}) error {
+	desc := fmt.Sprintf(format, args...)
+	st := status.New(codes.InvalidArgument, fmt.Sprintf("invalid %s: %s", field, desc))
+	if detailed, err := st.WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: desc}},
+	}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// notFound returns a NotFound error with a ResourceInfo detail.
+func notFound(resourceType, name string) error {
+	st := status.New(codes.NotFound, fmt.Sprintf("no such %s: %s", resourceType, name))
+	if detailed, err := st.WithDetails(&errdetails.ResourceInfo{ResourceType: resourceType, ResourceName: name}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// alreadyExists returns an AlreadyExists error with a ResourceInfo
+// detail.
+func alreadyExists(resourceType, name, desc string) error {
+	st := status.New(codes.AlreadyExists, fmt.Sprintf("%s %s: %s", resourceType, name, desc))
+	if detailed, err := st.WithDetails(&errdetails.ResourceInfo{ResourceType: resourceType, ResourceName: name, Description: desc}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// permissionDenied returns a PermissionDenied error with an ErrorInfo
+// detail, reason is an UPPER_SNAKE_CASE constant clients can match on.
+func permissionDenied(reason, format string, args ...interface{}) error {
+	st := status.New(codes.PermissionDenied, fmt.Sprintf(format, args...))
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// parseID parses the client id in field of a request.
+func parseID(field, id string) (uuid.UUID, error) {
+	if id == "" {
+		return uuid.Nil, invalidField(field, "is required")
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, invalidField(field, "%q is not a uuid", id)
+	}
+	return parsed, nil
+}
+
+// validateName checks a client name: 1 to MaxNameLength characters, no
+// control characters or surrounding spaces, and no @, which is reserved
+// for users of other servers.
+func validateName(field, name string) error {
+	switch n := utf8.RuneCountInString(name); {
+	case n == 0:
+		return invalidField(field, "is required")
+	case n > MaxNameLength:
+		return invalidField(field, "is %d characters long, limit is %d", n, MaxNameLength)
+	case !utf8.ValidString(name):
+		return invalidField(field, "is not valid UTF-8")
+	case strings.TrimSpace(name) != name:
+		return invalidField(field, "can not start or end with spaces")
+	case strings.Contains(name, "@"):
+		return invalidField(field, "can not contain @, it is reserved for users of other servers")
+	case strings.IndexFunc(name, unicode.IsControl) >= 0:
+		return invalidField(field, "can not contain control characters")
+	}
+	return nil
+}
+
+// validateChatMessage checks the fields of a Message request, maxLength
+// is the limit on the text.
+func validateChatMessage(in *pb.ChatMessage, maxLength int) error {
+	if _, err := parseID("sender_id", in.GetSenderId()); err != nil {
+		return err
+	}
+	// commands need no target, Message checks there is one otherwise
+	targets := 0
+	for _, t := range []string{in.GetRecipientId(), in.GetRecipientName(), in.GetRoom()} {
+		if t != "" {
+			targets++
+		}
+	}
+	if targets > 1 {
+		return invalidField("recipient_id", "only one of recipient_id, recipient_name and room can be set")
+	}
+	if in.GetRecipientId() != "" {
+		if _, err := parseID("recipient_id", in.GetRecipientId()); err != nil {
+			return err
+		}
+	}
+	return validateMessageText(in.GetText(), maxLength)
+}
+
+// validateMessageText checks the text of a message, whoever sent it.
+func validateMessageText(text string, maxLength int) error {
+	if strings.TrimSpace(text) == "" {
+		return invalidField("text", "is empty")
+	}
+	if !utf8.ValidString(text) {
+		return invalidField("text", "is not valid UTF-8")
+	}
+	if n := utf8.RuneCountInString(text); n > maxLength {
+		return invalidField("text", "is %d characters long, limit is %d", n, maxLength)
+	}
+	return nil
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+)
+
+const (
+	WebhookQueueSize   = 1000
+	WebhookMaxAttempts = 5
+	WebhookTimeout     = 10 * time.Second
+	WebhookBackoff     = time.Second
+	WebhookMaxBackoff  = time.Minute
+)
+
+// webhookConfig is one entry of the -webhooks file.
+type webhookConfig struct {
+	URL string `json:"url"`
+	// Secret signs the body, sent as X-GoChat-Signature: sha256=<hex hmac>.
+	Secret string `json:"secret"`
+	// Events filters what is sent, e.g. "message.sent" or "room.*",
+	// everything is sent when empty.
+	Events []string `json:"events"`
+}
+
+func (c webhookConfig) wants(kind serverEventKind) bool {
+	if len(c.Events) == 0 {
+		return true
+	}
+	name := kind.String()
+	for _, e := range c.Events {
+		if e == "*" || e == name {
+			return true
+		}
+		if prefix := strings.TrimSuffix(e, "*"); prefix != e && strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func loadWebhookConfigs(path string) ([]webhookConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var configs []webhookConfig
+	if err := json.Unmarshal(b, &configs); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", path, err)
+	}
+	for i, c := range configs {
+		if !strings.HasPrefix(c.URL, "http://") && !strings.HasPrefix(c.URL, "https://") {
+			return nil, fmt.Errorf("webhook %d: invalid url %q", i, c.URL)
+		}
+	}
+	return configs, nil
+}
+
+type webhookUser struct {
+	ID      string          `json:"id"`
+	Name    string          `json:"name"`
+	OldName string          `json:"old_name,omitempty"`
+	Profile *webhookProfile `json:"profile,omitempty"`
+}
+
+type webhookProfile struct {
+	DisplayName     string `json:"display_name,omitempty"`
+	Pronouns        string `json:"pronouns,omitempty"`
+	Bio             string `json:"bio,omitempty"`
+	TimeZone        string `json:"time_zone,omitempty"`
+	AvatarURL       string `json:"avatar_url,omitempty"`
+	Status          string `json:"status,omitempty"`
+	StatusExpiresAt int64  `json:"status_expires_at,omitempty"`
+}
+
+type webhookMessage struct {
+	Text        string            `json:"text"`
+	SenderID    string            `json:"sender_id"`
+	RecipientID string            `json:"recipient_id,omitempty"`
+	Room        string            `json:"room,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+type webhookRoom struct {
+	Name  string `json:"name"`
+	Topic string `json:"topic,omitempty"`
+}
+
+type webhookPayload struct {
+	ID      string          `json:"id"`
+	Event   string          `json:"event"`
+	Time    time.Time       `json:"time"`
+	User    *webhookUser    `json:"user,omitempty"`
+	Message *webhookMessage `json:"message,omitempty"`
+	Room    *webhookRoom    `json:"room,omitempty"`
+}
+
+func newWebhookPayload(ev serverEvent) webhookPayload {
+	p := webhookPayload{ID: uuid.NewString(), Event: ev.kind.String(), Time: time.Now().UTC()}
+	if ev.client.clientId != uuid.Nil {
+		p.User = &webhookUser{ID: ev.client.clientId.String(), Name: ev.client.name, OldName: ev.oldName}
+		if pr := visibleProfile(ev.client.profile, p.Time); pr != nil {
+			p.User.Profile = &webhookProfile{
+				DisplayName:     pr.GetDisplayName(),
+				Pronouns:        pr.GetPronouns(),
+				Bio:             pr.GetBio(),
+				TimeZone:        pr.GetTimeZone(),
+				AvatarURL:       pr.GetAvatar().GetUrl(),
+				Status:          pr.GetStatus(),
+				StatusExpiresAt: pr.GetStatusExpiresAt(),
+			}
+		}
+	}
+	if m := ev.message; m != nil {
+		p.Message = &webhookMessage{Text: m.text, SenderID: m.sender, RecipientID: m.recipient, Room: m.room, Metadata: m.metadata}
+	}
+	if ev.room != "" {
+		p.Room = &webhookRoom{Name: ev.room, Topic: ev.topic}
+	}
+	return p
+}
+
+type webhookDelivery struct {
+	payload webhookPayload
+	body    []byte
+}
+
+type webhook struct {
+	webhookConfig
+	queue chan webhookDelivery
+}
+
+// webhookDispatcher POSTs server events to the configured URLs. Each
+// webhook has its own queue and worker, so a slow endpoint only delays
+// itself. Deliveries that still fail after retrying, or did not fit in
+// the queue, are appended to the dead-letter file as JSON lines.
+type webhookDispatcher struct {
+	log    hclog.Logger
+	hooks  []*webhook
+	client *http.Client
+
+	deadLetterPath string
+	// written to the file by writeDeadLetters, which stops once the
+	// workers stopped and it wrote what is left
+	letters chan deadLetter
+	workers sync.WaitGroup
+	stopped chan struct{}
+	// closed once writeDeadLetters returned
+	done chan struct{}
+	// makes the workers give up, set by run
+	cancel context.CancelFunc
+}
+
+func newWebhookDispatcher(configs []webhookConfig, deadLetterPath string, log hclog.Logger) *webhookDispatcher {
+	d := &webhookDispatcher{
+		log:            log,
+		client:         &http.Client{Timeout: WebhookTimeout},
+		deadLetterPath: deadLetterPath,
+		letters:        make(chan deadLetter, WebhookQueueSize),
+		stopped:        make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	for _, c := range configs {
+		d.hooks = append(d.hooks, &webhook{webhookConfig: c, queue: make(chan webhookDelivery, WebhookQueueSize)})
+	}
+	return d
+}
+
+func (d *webhookDispatcher) run(ctx context.Context) {
+	ctx, d.cancel = context.WithCancel(ctx)
+	for _, h := range d.hooks {
+		d.workers.Add(1)
+		go func(h *webhook) {
+			defer d.workers.Done()
+			d.worker(ctx, h)
+		}(h)
+	}
+	go func() {
+		d.workers.Wait()
+		close(d.stopped)
+	}()
+	go d.writeDeadLetters()
+}
+
+// handleEvent is an eventListener, it never blocks. Deliveries that do
+// not fit in the queue of a webhook are dead-lettered.
+func (d *webhookDispatcher) handleEvent(ev serverEvent) {
+	var delivery *webhookDelivery
+	for _, h := range d.hooks {
+		if !h.wants(ev.kind) {
+			continue
+		}
+		if delivery == nil {
+			p := newWebhookPayload(ev)
+			body, err := json.Marshal(p)
+			if err != nil {
+				d.log.Error("could not encode payload", "error", err)
+				return
+			}
+			delivery = &webhookDelivery{payload: p, body: body}
+		}
+		select {
+		case h.queue <- *delivery:
+		default:
+			d.dropDelivery(h, *delivery)
+		}
+	}
+}
+
+// close stops taking events, the workers exit once they delivered what
+// is queued.
+func (d *webhookDispatcher) close() {
+	for _, h := range d.hooks {
+		close(h.queue)
+	}
+}
+
+// wait waits for the workers of a closed d to deliver what is queued and
+// for the dead letters to be written. Once ctx is done the workers give
+// up and dead-letter the deliveries they have left instead.
+func (d *webhookDispatcher) wait(ctx context.Context) {
+	select {
+	case <-d.done:
+		return
+	case <-ctx.Done():
+	}
+	d.log.Warn("deliveries did not finish in time, dead-lettering the rest")
+	d.cancel()
+	<-d.done
+}
+
+// configures reports whether d was made from configs and deadLetterPath.
+func (d *webhookDispatcher) configures(configs []webhookConfig, deadLetterPath string) bool {
+	if len(configs) != len(d.hooks) || deadLetterPath != d.deadLetterPath {
+		return false
+	}
+	for i, h := range d.hooks {
+		if !reflect.DeepEqual(configs[i], h.webhookConfig) {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *webhookDispatcher) worker(ctx context.Context, h *webhook) {
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case delivery, ok := <-h.queue:
+					if !ok {
+						return
+					}
+					d.deadLetter(h, delivery, 0, ctx.Err())
+				default:
+					return
+				}
+			}
+		case delivery, ok := <-h.queue:
+			if !ok {
+				return
+			}
+			d.deliver(ctx, h, delivery)
+		}
+	}
+}
+
+func (d *webhookDispatcher) deliver(ctx context.Context, h *webhook, delivery webhookDelivery) {
+	backoff := WebhookBackoff
+	var err error
+	for attempt := 1; attempt <= WebhookMaxAttempts; attempt++ {
+		var retry bool
+		retry, err = d.post(ctx, h, delivery)
+		if err == nil {
+			return
+		}
+		if !retry || attempt == WebhookMaxAttempts {
+			d.deadLetter(h, delivery, attempt, err)
+			return
+		}
+		d.log.Warn("delivery failed, retrying", "url", h.URL, "attempt", attempt, "error", err, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			d.deadLetter(h, delivery, attempt, ctx.Err())
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > WebhookMaxBackoff {
+			backoff = WebhookMaxBackoff
+		}
+	}
+}
+
+// post sends one delivery attempt and reports whether a failure is
+// worth retrying.
+func (d *webhookDispatcher) post(ctx context.Context, h *webhook, delivery webhookDelivery) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(delivery.body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-chat-webhooks")
+	req.Header.Set("X-GoChat-Event", delivery.payload.Event)
+	req.Header.Set("X-GoChat-Delivery", delivery.payload.ID)
+	if h.Secret != "" {
+		req.Header.Set("X-GoChat-Signature", "sha256="+signWebhook(h.Secret, delivery.body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("unexpected status: %s", resp.Status)
+	default:
+		return false, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+}
+
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type deadLetter struct {
+	Time     time.Time       `json:"time"`
+	URL      string          `json:"url"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// deadLetter hands a delivery the worker gave up on to writeDeadLetters.
+func (d *webhookDispatcher) deadLetter(h *webhook, delivery webhookDelivery, attempts int, cause error) {
+	d.log.Error("gave up on delivery", "url", h.URL, "event", delivery.payload.Event, "delivery_id", delivery.payload.ID, "error", cause)
+	if d.deadLetterPath == "" {
+		return
+	}
+	d.letters <- deadLetter{Time: time.Now().UTC(), URL: h.URL, Attempts: attempts, Error: cause.Error(), Payload: delivery.body}
+}
+
+// dropDelivery is deadLetter for handleEvent, it does not wait for
+// writeDeadLetters either. Drops are counted whether they make it to
+// the file or not.
+func (d *webhookDispatcher) dropDelivery(h *webhook, delivery webhookDelivery) {
+	messagesDropped.WithLabelValues("webhook").Inc()
+	if d.deadLetterPath == "" {
+		return
+	}
+	select {
+	case d.letters <- deadLetter{Time: time.Now().UTC(), URL: h.URL, Error: "queue full", Payload: delivery.body}:
+	default:
+	}
+}
+
+// writeDeadLetters appends dead letters to the file until the workers
+// stopped.
+func (d *webhookDispatcher) writeDeadLetters() {
+	defer close(d.done)
+	for {
+		select {
+		case l := <-d.letters:
+			d.writeDeadLetter(l)
+		case <-d.stopped:
+			for {
+				select {
+				case l := <-d.letters:
+					d.writeDeadLetter(l)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (d *webhookDispatcher) writeDeadLetter(l deadLetter) {
+	line, err := json.Marshal(l)
+	if err != nil {
+		d.log.Error("could not encode dead letter", "error", err)
+		return
+	}
+	f, err := os.OpenFile(d.deadLetterPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		d.log.Error("could not open dead letter log", "error", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		d.log.Error("could not write dead letter log", "error", err)
+	}
+}
+
+// notifyWebhooks is the eventListener of the current webhookDispatcher,
+// which reload may replace.
+func (s *server) notifyWebhooks(ev serverEvent) {
+	s.webhooksMu.RLock()
+	defer s.webhooksMu.RUnlock()
+	if s.webhooks != nil {
+		s.webhooks.handleEvent(ev)
+	}
+}
+
+// setWebhooks starts d, which may be nil, and closes the dispatcher it
+// replaces. That one is returned, if any, to wait for.
+func (s *server) setWebhooks(d *webhookDispatcher) *webhookDispatcher {
+	if d != nil {
+		d.run(context.Background())
+	}
+	s.webhooksMu.Lock()
+	old := s.webhooks
+	s.webhooks = d
+	s.webhooksMu.Unlock()
+	if old != nil {
+		old.close()
+	}
+	return old
+}
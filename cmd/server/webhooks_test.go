@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+)
+
+type webhookRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookEndpoint answers the deliveries it receives with the given
+// statuses in turn, the last one repeating, and passes them on.
+func webhookEndpoint(t *testing.T, statuses ...int) (*httptest.Server, <-chan webhookRequest) {
+	t.Helper()
+	requests := make(chan webhookRequest, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- webhookRequest{header: r.Header, body: body}
+		code := statuses[0]
+		if len(statuses) > 1 {
+			statuses = statuses[1:]
+		}
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, requests
+}
+
+func nextWebhookRequest(t *testing.T, requests <-chan webhookRequest) webhookRequest {
+	t.Helper()
+	select {
+	case r := <-requests:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("no delivery")
+		return webhookRequest{}
+	}
+}
+
+func connectedEvent(name string) serverEvent {
+	return serverEvent{kind: clientConnected, client: client{clientId: uuid.New(), name: name}}
+}
+
+func TestWebhookRetriesAndSigns(t *testing.T) {
+	srv, requests := webhookEndpoint(t, http.StatusServiceUnavailable, http.StatusOK)
+	d := newWebhookDispatcher([]webhookConfig{{URL: srv.URL, Secret: "s3cret"}}, "", hclog.NewNullLogger())
+	d.run(context.Background())
+	defer d.close()
+
+	d.handleEvent(connectedEvent("alice"))
+	first := nextWebhookRequest(t, requests)
+	second := nextWebhookRequest(t, requests)
+
+	if first.header.Get("X-GoChat-Delivery") != second.header.Get("X-GoChat-Delivery") {
+		t.Error("retry was sent as another delivery")
+	}
+	if got, want := second.header.Get("X-GoChat-Signature"), "sha256="+signWebhook("s3cret", second.body); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if got := second.header.Get("X-GoChat-Event"); got != "client.connected" {
+		t.Errorf("event header = %q, want client.connected", got)
+	}
+	var p webhookPayload
+	if err := json.Unmarshal(second.body, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Event != "client.connected" || p.User == nil || p.User.Name != "alice" {
+		t.Errorf("payload = %+v, want alice connecting", p)
+	}
+}
+
+func TestWebhookDeadLetters(t *testing.T) {
+	srv, requests := webhookEndpoint(t, http.StatusBadRequest)
+	path := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+	d := newWebhookDispatcher([]webhookConfig{{URL: srv.URL}}, path, hclog.NewNullLogger())
+	d.run(context.Background())
+
+	d.handleEvent(connectedEvent("alice"))
+	nextWebhookRequest(t, requests)
+	d.close()
+	<-d.done
+
+	letters := readDeadLetters(t, path)
+	if len(letters) != 1 {
+		t.Fatalf("got %d dead letters, want 1", len(letters))
+	}
+	l := letters[0]
+	if l.URL != srv.URL || l.Attempts != 1 || !strings.Contains(l.Error, "400") {
+		t.Errorf("dead letter = %+v, want one attempt failing with 400", l)
+	}
+	var p webhookPayload
+	if err := json.Unmarshal(l.Payload, &p); err != nil || p.Event != "client.connected" {
+		t.Errorf("payload = %s, want the delivery (%v)", l.Payload, err)
+	}
+	select {
+	case <-requests:
+		t.Error("a client error was retried")
+	default:
+	}
+}
+
+func TestWebhookWaitDrains(t *testing.T) {
+	srv, requests := webhookEndpoint(t, http.StatusOK)
+	d := newWebhookDispatcher([]webhookConfig{{URL: srv.URL}}, "", hclog.NewNullLogger())
+	d.run(context.Background())
+	d.handleEvent(connectedEvent("alice"))
+	d.handleEvent(connectedEvent("bob"))
+	d.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	d.wait(ctx)
+	if len(requests) != 2 {
+		t.Errorf("%d deliveries sent before wait returned, want 2", len(requests))
+	}
+}
+
+func TestWebhookWaitTimeout(t *testing.T) {
+	srv, _ := webhookEndpoint(t, http.StatusServiceUnavailable)
+	path := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+	d := newWebhookDispatcher([]webhookConfig{{URL: srv.URL}}, path, hclog.NewNullLogger())
+	d.run(context.Background())
+	for _, name := range []string{"alice", "bob", "carol"} {
+		d.handleEvent(connectedEvent(name))
+	}
+	d.close()
+
+	// the first delivery is retrying, the others are queued
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	d.wait(ctx)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("wait took %s, the workers did not give up", elapsed)
+	}
+	if letters := readDeadLetters(t, path); len(letters) != 3 {
+		t.Errorf("got %d dead letters, want all 3 deliveries", len(letters))
+	}
+}
+
+func TestWebhookQueueFull(t *testing.T) {
+	d := newWebhookDispatcher([]webhookConfig{{URL: "http://localhost:1"}}, filepath.Join(t.TempDir(), "dl.jsonl"), hclog.NewNullLogger())
+	// not running, nothing takes deliveries off the queue
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i <= WebhookQueueSize; i++ {
+			d.handleEvent(connectedEvent("alice"))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handleEvent blocked on a full queue")
+	}
+	if len(d.letters) != 1 {
+		t.Fatalf("%d deliveries dead-lettered, want 1", len(d.letters))
+	}
+	if l := <-d.letters; l.Error != "queue full" {
+		t.Errorf("dead letter error = %q, want queue full", l.Error)
+	}
+}
+
+func TestWebhookEventFilter(t *testing.T) {
+	tests := []struct {
+		events []string
+		kind   serverEventKind
+		want   bool
+	}{
+		{nil, messageSent, true},
+		{[]string{"*"}, roomLeft, true},
+		{[]string{"message.sent"}, messageSent, true},
+		{[]string{"message.sent"}, roomJoined, false},
+		{[]string{"room.*"}, roomJoined, true},
+		{[]string{"room.*"}, clientConnected, false},
+	}
+	for _, tt := range tests {
+		if got := (webhookConfig{Events: tt.events}).wants(tt.kind); got != tt.want {
+			t.Errorf("%v wants %s = %v, want %v", tt.events, tt.kind, got, tt.want)
+		}
+	}
+}
+
+func readDeadLetters(t *testing.T, path string) []deadLetter {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var letters []deadLetter
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var l deadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+			t.Fatal(err)
+		}
+		letters = append(letters, l)
+	}
+	return letters
+}
+
+func TestIncomingWebhookLimits(t *testing.T) {
+	s := newTestServer(t)
+	s.limiter = newRateLimiter(s, hclog.NewNullLogger())
+	h := &incomingWebhookHandler{s: s, integrations: []integration{{Name: "ci", Token: "ci-token-0123456789"}}}
+	post := func(text string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(incomingMessage{Text: text, Room: "#builds"})
+		r := httptest.NewRequest(http.MethodPost, "/hooks/incoming", strings.NewReader(string(body)))
+		r.Header.Set("Authorization", "Bearer ci-token-0123456789")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := post(strings.Repeat("x", DefaultMaxMessageLength+1)); w.Code != http.StatusBadRequest {
+		t.Errorf("too long text: got %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := post(" "); w.Code != http.StatusBadRequest {
+		t.Errorf("blank text: got %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	for i := 0; i < UserBurst; i++ {
+		if _, err := s.limiter.allowIntegration("ci", "other"); err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+	}
+	w := post("build passed")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("over the limit: got %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("no Retry-After header")
+	}
+}
+
+func TestIncomingWebhookAuthenticate(t *testing.T) {
+	h := &incomingWebhookHandler{integrations: []integration{{Name: "ci", Token: "ci-token-0123456789"}}}
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"bearer token", "Bearer ci-token-0123456789", true},
+		{"bare token", "ci-token-0123456789", false},
+		{"other scheme", "Basic ci-token-0123456789", false},
+		{"wrong token", "Bearer ci-token-9876543210", false},
+		{"empty token", "Bearer ", false},
+		{"no header", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/hooks/incoming", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if _, ok := h.authenticate(r); ok != tt.want {
+				t.Errorf("authenticated %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hashicorp/go-hclog"
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	WebSocketMaxFrame     = 64 << 10
+	WebSocketWriteTimeout = 10 * time.Second
+	WebSocketPongTimeout  = time.Minute
+	WebSocketPingInterval = WebSocketPongTimeout * 9 / 10
+)
+
+// Websocket subprotocols. Frames are protojson text frames unless the
+// client negotiates binary protobuf frames.
+const (
+	wsProtocolJSON  = "gochat.json"
+	wsProtocolProto = "gochat.proto"
+)
+
+// websocketHandler serves the chat over websockets for browsers and other
+// clients that can not speak grpc. Clients send pb.ClientFrame and receive
+// pb.ServerFrame, see message.proto. Like the gateway, it is a grpc client
+// of this same server, the client is disconnected when the socket closes.
+type websocketHandler struct {
+	log      hclog.Logger
+	api      pb.ChatServerClient
+	upgrader websocket.Upgrader
+}
+
+// newWebsocketHandler only accepts same origin requests unless origins
+// lists the allowed ones, "*" allows all.
+func newWebsocketHandler(conn *grpc.ClientConn, origins []string, log hclog.Logger) *websocketHandler {
+	h := &websocketHandler{
+		log: log,
+		api: pb.NewChatServerClient(conn),
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{wsProtocolJSON, wsProtocolProto},
+		},
+	}
+	if len(origins) > 0 {
+		h.upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			for _, o := range origins {
+				if o == "*" || o == origin {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return h
+}
+
+func (h *websocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader already replied
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(gatewayContext(r))
+	defer cancel()
+	ws := &wsSession{
+		log:    h.log.With("remote_addr", conn.RemoteAddr().String()),
+		api:    h.api,
+		conn:   conn,
+		binary: conn.Subprotocol() == wsProtocolProto,
+		cancel: cancel,
+	}
+	ws.run(ctx)
+	cancel()
+	ws.disconnect()
+}
+
+type wsSession struct {
+	log    hclog.Logger
+	api    pb.ChatServerClient
+	conn   *websocket.Conn
+	binary bool
+	cancel context.CancelFunc
+
+	writeMu sync.Mutex
+
+	// only used by the read loop
+	clientId string
+}
+
+func (ws *wsSession) run(ctx context.Context) {
+	ws.conn.SetReadLimit(WebSocketMaxFrame)
+	ws.conn.SetReadDeadline(time.Now().Add(WebSocketPongTimeout))
+	ws.conn.SetPongHandler(func(string) error {
+		return ws.conn.SetReadDeadline(time.Now().Add(WebSocketPongTimeout))
+	})
+	go ws.ping(ctx)
+
+	for {
+		kind, b, err := ws.conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				ws.log.Warn("read failed", "error", err)
+			}
+			return
+		}
+		var frame pb.ClientFrame
+		if kind == websocket.BinaryMessage {
+			err = proto.Unmarshal(b, &frame)
+		} else {
+			err = protojson.Unmarshal(b, &frame)
+		}
+		if err != nil {
+			ws.writeError("", status.Errorf(codes.InvalidArgument, "invalid frame: %v", err))
+			continue
+		}
+		ws.handle(ctx, &frame)
+	}
+}
+
+// disconnect ends the client of the session right away, it can not
+// resume without the socket.
+func (ws *wsSession) disconnect() {
+	if ws.clientId == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), WebSocketWriteTimeout)
+	defer cancel()
+	if _, err := ws.api.Disconnect(ctx, &pb.DisconnectRequest{ClientId: ws.clientId}); err != nil && status.Code(err) != codes.NotFound {
+		ws.log.Warn("could not disconnect client", "client_id", ws.clientId, "error", err)
+	}
+}
+
+func (ws *wsSession) ping(ctx context.Context) {
+	ticker := time.NewTicker(WebSocketPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ws.writeMu.Lock()
+			err := ws.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(WebSocketWriteTimeout))
+			ws.writeMu.Unlock()
+			if err != nil {
+				ws.cancel()
+				return
+			}
+		}
+	}
+}
+
+func (ws *wsSession) handle(ctx context.Context, frame *pb.ClientFrame) {
+	reply := &pb.ServerFrame{Id: frame.GetId()}
+	switch f := frame.GetFrame().(type) {
+	case *pb.ClientFrame_Connect:
+		resp, err := ws.connect(ctx, f.Connect)
+		if err != nil {
+			ws.writeError(frame.GetId(), err)
+			return
+		}
+		reply.Frame = &pb.ServerFrame_Connected{Connected: resp}
+	case *pb.ClientFrame_Message:
+		if ws.clientId == "" {
+			ws.writeError(frame.GetId(), status.Errorf(codes.FailedPrecondition, "not connected"))
+			return
+		}
+		m := f.Message
+		if m.GetSenderId() == "" {
+			m.SenderId = ws.clientId
+		}
+		if m.GetSenderId() != ws.clientId {
+			ws.writeError(frame.GetId(), status.Errorf(codes.PermissionDenied, "can only send as %s", ws.clientId))
+			return
+		}
+		resp, err := ws.api.Message(ctx, m)
+		if err != nil {
+			ws.writeError(frame.GetId(), err)
+			return
+		}
+		reply.Frame = &pb.ServerFrame_Sent{Sent: resp}
+	case *pb.ClientFrame_Clients:
+		resp, err := ws.api.GetConnectedClients(ctx, f.Clients)
+		if err != nil {
+			ws.writeError(frame.GetId(), err)
+			return
+		}
+		reply.Frame = &pb.ServerFrame_Clients{Clients: resp}
+	case *pb.ClientFrame_Resolve:
+		resp, err := ws.api.ResolveUser(ctx, f.Resolve)
+		if err != nil {
+			ws.writeError(frame.GetId(), err)
+			return
+		}
+		reply.Frame = &pb.ServerFrame_Resolved{Resolved: resp}
+	case *pb.ClientFrame_ChangeName:
+		if ws.clientId == "" {
+			ws.writeError(frame.GetId(), status.Errorf(codes.FailedPrecondition, "not connected"))
+			return
+		}
+		req := f.ChangeName
+		if req.GetClientId() == "" {
+			req.ClientId = ws.clientId
+		}
+		if req.GetClientId() != ws.clientId {
+			ws.writeError(frame.GetId(), status.Errorf(codes.PermissionDenied, "can only rename %s", ws.clientId))
+			return
+		}
+		resp, err := ws.api.ChangeName(ctx, req)
+		if err != nil {
+			ws.writeError(frame.GetId(), err)
+			return
+		}
+		reply.Frame = &pb.ServerFrame_NameChanged{NameChanged: resp}
+	case *pb.ClientFrame_GetProfile:
+		if ws.clientId == "" {
+			ws.writeError(frame.GetId(), status.Errorf(codes.FailedPrecondition, "not connected"))
+			return
+		}
+		req := f.GetProfile
+		if req.GetClientId() == "" {
+			req.ClientId = ws.clientId
+		}
+		resp, err := ws.api.GetProfile(ctx, req)
+		if err != nil {
+			ws.writeError(frame.GetId(), err)
+			return
+		}
+		reply.Frame = &pb.ServerFrame_Profile{Profile: resp}
+	case *pb.ClientFrame_UpdateProfile:
+		if ws.clientId == "" {
+			ws.writeError(frame.GetId(), status.Errorf(codes.FailedPrecondition, "not connected"))
+			return
+		}
+		req := f.UpdateProfile
+		if req.GetClientId() == "" {
+			req.ClientId = ws.clientId
+		}
+		if req.GetClientId() != ws.clientId {
+			ws.writeError(frame.GetId(), status.Errorf(codes.PermissionDenied, "can only update the profile of %s", ws.clientId))
+			return
+		}
+		resp, err := ws.api.UpdateProfile(ctx, req)
+		if err != nil {
+			ws.writeError(frame.GetId(), err)
+			return
+		}
+		reply.Frame = &pb.ServerFrame_ProfileUpdated{ProfileUpdated: resp}
+	default:
+		ws.writeError(frame.GetId(), status.Errorf(codes.InvalidArgument, "empty frame"))
+		return
+	}
+	ws.write(reply)
+}
+
+// connect connects and subscribes to events, which are forwarded until
+// the stream or the socket ends.
+func (ws *wsSession) connect(ctx context.Context, req *pb.ConnectRequest) (*pb.ConnectResponse, error) {
+	if ws.clientId != "" {
+		return nil, status.Errorf(codes.FailedPrecondition, "already connected as %s", ws.clientId)
+	}
+	resp, err := ws.api.Connect(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	ws.clientId = resp.GetClientId()
+	stream, err := ws.api.ReceiveEvents(ctx, &pb.ReceiveRequest{ClientId: ws.clientId})
+	if err == nil {
+		err = waitSubscribed(stream, &pb.Event{})
+	}
+	if err != nil {
+		// the client would be kept until the socket closes otherwise,
+		// and another connect could not take its name
+		ws.disconnect()
+		ws.clientId = ""
+		return nil, err
+	}
+
+	go func() {
+		// the read loop stops once the socket is closed
+		defer ws.conn.Close()
+		for {
+			ev, err := stream.Recv()
+			if err != nil {
+				if err != io.EOF && ctx.Err() == nil {
+					ws.writeError("", err)
+				}
+				return
+			}
+			if err := ws.write(&pb.ServerFrame{Frame: &pb.ServerFrame_Event{Event: ev}}); err != nil {
+				return
+			}
+		}
+	}()
+	return resp, nil
+}
+
+func (ws *wsSession) writeError(id string, err error) {
+	st := status.Convert(err)
+	ws.write(&pb.ServerFrame{Id: id, Frame: &pb.ServerFrame_Error{Error: &pb.FrameError{
+		Code:    st.Code().String(),
+		Message: st.Message(),
+	}}})
+}
+
+func (ws *wsSession) write(frame *pb.ServerFrame) error {
+	kind := websocket.TextMessage
+	var b []byte
+	var err error
+	if ws.binary {
+		kind = websocket.BinaryMessage
+		b, err = proto.Marshal(frame)
+	} else {
+		b, err = gatewayMarshal.Marshal(frame)
+	}
+	if err != nil {
+		ws.log.Error("could not encode frame", "error", err)
+		return err
+	}
+
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	ws.conn.SetWriteDeadline(time.Now().Add(WebSocketWriteTimeout))
+	if err := ws.conn.WriteMessage(kind, b); err != nil {
+		ws.cancel()
+		return err
+	}
+	return nil
+}
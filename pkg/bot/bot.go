@@ -0,0 +1,232 @@
+// Package bot runs chat bots on top of pkg/client. A bot registers
+// slash-style commands, each with its own handler, and answers them in the
+// conversation they were sent in.
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/wmolicki/go-chat/pkg/client"
+)
+
+const Prefix = "/"
+
+var ErrUsage = errors.New("invalid usage")
+
+// Handler handles a single command invocation. Returning an error wrapping
+// ErrUsage replies with the command usage, any other error is reported to
+// the sender.
+type Handler func(ctx context.Context, req *Request) error
+
+type Command struct {
+	// Name is matched without the leading slash, e.g. "roll".
+	Name string
+	// Usage describes the arguments, e.g. "[NdM]".
+	Usage string
+	Help  string
+	// MinArgs and MaxArgs bound the number of arguments, MaxArgs < 0
+	// means no upper bound.
+	MinArgs int
+	MaxArgs int
+	// Raw passes Text as typed and leaves Args empty, for commands taking
+	// free text. MinArgs > 0 then only requires some text.
+	Raw     bool
+	Handler Handler
+}
+
+func (c *Command) usage() string {
+	if c.Usage == "" {
+		return Prefix + c.Name
+	}
+	return Prefix + c.Name + " " + c.Usage
+}
+
+// Request is a parsed command invocation.
+type Request struct {
+	Command *Command
+	From    client.User
+	// Room is set when the command was sent to a room.
+	Room string
+	Args []string
+	// Text is everything after the command name, unparsed.
+	Text string
+
+	bot *Bot
+}
+
+// Reply answers in the room or conversation the command came from.
+func (r *Request) Reply(ctx context.Context, text string) error {
+	if r.Room != "" {
+		return r.bot.client.SendRoom(ctx, r.Room, text)
+	}
+	return r.bot.client.SendTo(ctx, r.From.ID, text)
+}
+
+func (r *Request) Replyf(ctx context.Context, format string, args ...interface{}) error {
+	return r.Reply(ctx, fmt.Sprintf(format, args...))
+}
+
+type Bot struct {
+	client *client.Client
+	// Logger receives errors handling messages, log.Default() if nil.
+	Logger client.Logger
+
+	mu       sync.Mutex
+	commands map[string]*Command
+	// Fallback is called for messages that are not commands, if set.
+	Fallback func(ctx context.Context, m *client.MessageEvent) error
+}
+
+// New creates a bot answering on c. A /help command is registered.
+func New(c *client.Client) *Bot {
+	b := &Bot{client: c, commands: make(map[string]*Command)}
+	b.Handle(Command{
+		Name:    "help",
+		Usage:   "[command]",
+		Help:    "list commands or show help for one",
+		MaxArgs: 1,
+		Handler: b.help,
+	})
+	return b
+}
+
+// Handle registers cmd, replacing any command with the same name.
+func (b *Bot) Handle(cmd Command) {
+	if cmd.Handler == nil {
+		panic(fmt.Sprintf("bot: command %s has no handler", cmd.Name))
+	}
+	cmd.Name = strings.TrimPrefix(cmd.Name, Prefix)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.commands[cmd.Name] = &cmd
+}
+
+// Commands returns registered commands sorted by name.
+func (b *Bot) Commands() []*Command {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cmds := make([]*Command, 0, len(b.commands))
+	for _, c := range b.commands {
+		cmds = append(cmds, c)
+	}
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name < cmds[j].Name })
+	return cmds
+}
+
+// Run handles incoming messages one at a time until ctx is done or the
+// client is closed. Messages the bot sent itself are ignored. It fails
+// right away if a command is one the server runs itself, messages
+// invoking it would never reach the bot.
+func (b *Bot) Run(ctx context.Context) error {
+	if err := b.checkCommands(ctx); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev, ok := <-b.client.Events():
+			if !ok {
+				return client.ErrClosed
+			}
+			m, ok := ev.(*client.MessageEvent)
+			if !ok || m.From.ID == b.client.ID() {
+				continue
+			}
+			if err := b.dispatch(ctx, m); err != nil {
+				b.logger().Printf("bot: handling message from %s: %v", m.From, err)
+			}
+		}
+	}
+}
+
+func (b *Bot) checkCommands(ctx context.Context) error {
+	server, err := b.client.ServerCommands(ctx)
+	if err != nil {
+		return fmt.Errorf("bot: %w", err)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, sc := range server {
+		for name := range b.commands {
+			if strings.EqualFold(name, sc.Name) {
+				return fmt.Errorf("bot: %s%s is run by the server", Prefix, name)
+			}
+		}
+	}
+	return nil
+}
+
+func (b *Bot) logger() client.Logger {
+	if b.Logger == nil {
+		return log.Default()
+	}
+	return b.Logger
+}
+
+func (b *Bot) dispatch(ctx context.Context, m *client.MessageEvent) error {
+	text := strings.TrimSpace(m.Text)
+	if !strings.HasPrefix(text, Prefix) {
+		if b.Fallback == nil {
+			return nil
+		}
+		return b.Fallback(ctx, m)
+	}
+
+	name, rest, _ := strings.Cut(strings.TrimPrefix(text, Prefix), " ")
+	b.mu.Lock()
+	cmd, ok := b.commands[name]
+	b.mu.Unlock()
+	req := &Request{Command: cmd, From: m.From, Room: m.Room, Text: strings.TrimSpace(rest), bot: b}
+	if !ok {
+		return req.Replyf(ctx, "unknown command %s%s, try %shelp", Prefix, name, Prefix)
+	}
+
+	if cmd.Raw {
+		if cmd.MinArgs > 0 && req.Text == "" {
+			return req.Replyf(ctx, "usage: %s", cmd.usage())
+		}
+	} else {
+		args, err := SplitArgs(req.Text)
+		if err != nil {
+			return req.Replyf(ctx, "%v, usage: %s", err, cmd.usage())
+		}
+		if len(args) < cmd.MinArgs || (cmd.MaxArgs >= 0 && len(args) > cmd.MaxArgs) {
+			return req.Replyf(ctx, "usage: %s", cmd.usage())
+		}
+		req.Args = args
+	}
+
+	err := cmd.Handler(ctx, req)
+	if errors.Is(err, ErrUsage) {
+		return req.Replyf(ctx, "%v, usage: %s", err, cmd.usage())
+	}
+	if err != nil {
+		return req.Replyf(ctx, "%s%s failed: %v", Prefix, cmd.Name, err)
+	}
+	return nil
+}
+
+func (b *Bot) help(ctx context.Context, req *Request) error {
+	if len(req.Args) == 1 {
+		b.mu.Lock()
+		cmd, ok := b.commands[strings.TrimPrefix(req.Args[0], Prefix)]
+		b.mu.Unlock()
+		if !ok {
+			return fmt.Errorf("%w: no such command %s", ErrUsage, req.Args[0])
+		}
+		return req.Replyf(ctx, "%s - %s", cmd.usage(), cmd.Help)
+	}
+
+	var lines []string
+	for _, cmd := range b.Commands() {
+		lines = append(lines, fmt.Sprintf("%s - %s", cmd.usage(), cmd.Help))
+	}
+	return req.Reply(ctx, strings.Join(lines, "\n"))
+}
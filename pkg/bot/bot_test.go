@@ -0,0 +1,229 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wmolicki/go-chat/pkg/client"
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeServer accepts a single client, passes it the events sent on events
+// and records the messages it sends.
+type fakeServer struct {
+	pb.UnimplementedChatServerServer
+
+	mu       sync.Mutex
+	commands []*pb.ListCommandsResponse_Command
+
+	events chan *pb.Event
+	sent   chan *pb.ChatMessage
+}
+
+func (f *fakeServer) Connect(ctx context.Context, in *pb.ConnectRequest) (*pb.ConnectResponse, error) {
+	return &pb.ConnectResponse{ClientId: uuid.NewString()}, nil
+}
+
+func (f *fakeServer) Disconnect(ctx context.Context, in *pb.DisconnectRequest) (*pb.DisconnectResponse, error) {
+	return &pb.DisconnectResponse{}, nil
+}
+
+func (f *fakeServer) GetConnectedClients(ctx context.Context, in *pb.ConnectedClientsRequest) (*pb.ConnectedClientsResponse, error) {
+	return &pb.ConnectedClientsResponse{}, nil
+}
+
+func (f *fakeServer) ListCommands(ctx context.Context, in *pb.ListCommandsRequest) (*pb.ListCommandsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &pb.ListCommandsResponse{Commands: f.commands}, nil
+}
+
+func (f *fakeServer) Message(ctx context.Context, in *pb.ChatMessage) (*pb.MessageResponse, error) {
+	f.sent <- in
+	return &pb.MessageResponse{}, nil
+}
+
+func (f *fakeServer) ReceiveEvents(in *pb.ReceiveRequest, stream pb.ChatServer_ReceiveEventsServer) error {
+	if err := stream.SendHeader(metadata.Pairs("gochat-subscribed", "true")); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case ev := <-f.events:
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// startBot serves a fakeServer over bufconn and returns a bot connected
+// to it, with a few commands registered.
+func startBot(t *testing.T, commands ...*pb.ListCommandsResponse_Command) (*fakeServer, *Bot) {
+	t.Helper()
+	f := &fakeServer{commands: commands, events: make(chan *pb.Event), sent: make(chan *pb.ChatMessage, 10)}
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	pb.RegisterChatServerServer(srv, f)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := client.Dial(ctx, "bufnet", "bot",
+		client.WithDialOptions(grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials())),
+	)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	b := New(c)
+	b.Handle(Command{Name: "roll", Usage: "[NdM]", Help: "roll dice", MaxArgs: 1, Handler: func(ctx context.Context, req *Request) error {
+		return req.Reply(ctx, strings.TrimSpace("rolled "+strings.Join(req.Args, " ")))
+	}})
+	b.Handle(Command{Name: "say", Usage: "<words>", MinArgs: 1, MaxArgs: -1, Handler: func(ctx context.Context, req *Request) error {
+		return req.Reply(ctx, strings.Join(req.Args, "|"))
+	}})
+	b.Handle(Command{Name: "echo", Usage: "<text>", MinArgs: 1, Raw: true, Handler: func(ctx context.Context, req *Request) error {
+		return req.Reply(ctx, req.Text)
+	}})
+	b.Handle(Command{Name: "fail", Handler: func(ctx context.Context, req *Request) error {
+		return fmt.Errorf("%w: bad", ErrUsage)
+	}})
+	b.Handle(Command{Name: "boom", Handler: func(ctx context.Context, req *Request) error {
+		return errors.New("boom")
+	}})
+	return f, b
+}
+
+func (f *fakeServer) reply(t *testing.T) *pb.ChatMessage {
+	t.Helper()
+	select {
+	case m := <-f.sent:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reply")
+		return nil
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+		err  error
+	}{
+		{"empty", "", nil, nil},
+		{"words", "a  b\tc", []string{"a", "b", "c"}, nil},
+		{"double quotes", `"a b" c`, []string{"a b", "c"}, nil},
+		{"single quotes", `'a "b"' c`, []string{`a "b"`, "c"}, nil},
+		{"empty quotes", `"" a`, []string{"", "a"}, nil},
+		{"quotes inside a word", `a"b c"d`, []string{"ab cd"}, nil},
+		{"escaped space", `a\ b c`, []string{"a b", "c"}, nil},
+		{"escaped quote", `don\'t`, []string{"don't"}, nil},
+		{"unterminated quote", `"a b`, nil, errUnterminatedQuote},
+		{"apostrophe", `don't`, nil, errUnterminatedQuote},
+		{"trailing escape", `a\`, nil, errTrailingEscape},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitArgs(tt.in)
+			if err != tt.err || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, %v, want %q, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	f, b := startBot(t)
+	alice := client.User{ID: uuid.New(), Name: "alice"}
+	tests := []struct {
+		name string
+		room string
+		text string
+		want string
+	}{
+		{"no args", "", "/roll", "rolled"},
+		{"in a room", "#dice", "/roll 2d6", "rolled 2d6"},
+		{"too many args", "", "/roll 1 2", "usage: /roll [NdM]"},
+		{"too few args", "#dice", "/say", "usage: /say <words>"},
+		{"quoted args", "", `/say "a b" c\ d`, "a b|c d"},
+		{"unterminated quote", "", `/say "a b`, "unterminated quote, usage: /say <words>"},
+		{"raw text", "", "/echo don't  stop", "don't  stop"},
+		{"raw without text", "", "/echo", "usage: /echo <text>"},
+		{"usage error", "", "/fail", "invalid usage: bad, usage: /fail"},
+		{"handler error", "", "/boom", "/boom failed: boom"},
+		{"unknown command", "", "/nope", "unknown command /nope, try /help"},
+		{"help for a command", "", "/help roll", "/roll [NdM] - roll dice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := b.dispatch(context.Background(), &client.MessageEvent{From: alice, Room: tt.room, Text: tt.text})
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := f.reply(t)
+			if m.GetText() != tt.want {
+				t.Errorf("replied %q, want %q", m.GetText(), tt.want)
+			}
+			if tt.room != "" && m.GetRoom() != tt.room {
+				t.Errorf("replied to room %q, want %q", m.GetRoom(), tt.room)
+			}
+			if tt.room == "" && m.GetRecipientId() != alice.ID.String() {
+				t.Errorf("replied to %q, want alice %s", m.GetRecipientId(), alice.ID)
+			}
+		})
+	}
+}
+
+func TestRunIgnoresOwnMessages(t *testing.T) {
+	f, b := startBot(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+
+	alice := uuid.New()
+	for _, m := range []*pb.ChatMessage{
+		{SenderId: b.client.ID().String(), Text: "/roll"},
+		{SenderId: alice.String(), Text: "/roll 1d4"},
+	} {
+		select {
+		case f.events <- &pb.Event{Event: &pb.Event_Message{Message: m}}:
+		case <-time.After(5 * time.Second):
+			t.Fatal("bot did not receive the message")
+		}
+	}
+	if m := f.reply(t); m.GetText() != "rolled 1d4" || m.GetRecipientId() != alice.String() {
+		t.Errorf("first reply is %q to %s, want the one to alice", m.GetText(), m.GetRecipientId())
+	}
+}
+
+func TestRunRejectsServerCommands(t *testing.T) {
+	_, b := startBot(t, &pb.ListCommandsResponse_Command{Name: "nick"})
+	b.Handle(Command{Name: "Nick", Handler: func(ctx context.Context, req *Request) error { return nil }})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.Run(ctx); err == nil || !strings.Contains(err.Error(), "/Nick is run by the server") {
+		t.Errorf("Run returned %v, want /Nick to be refused", err)
+	}
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff computes exponentially growing delays between reconnect attempts.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	Factor  float64
+	// Jitter randomizes each delay by up to this fraction of it.
+	Jitter float64
+}
+
+var DefaultBackoff = Backoff{
+	Initial: 500 * time.Millisecond,
+	Max:     30 * time.Second,
+	Factor:  2,
+	Jitter:  0.2,
+}
+
+// Delay returns how long to wait before the given attempt (starting at 0).
+func (b Backoff) Delay(attempt int) time.Duration {
+	d := float64(b.Initial)
+	for i := 0; i < attempt && d < float64(b.Max); i++ {
+		d *= b.Factor
+	}
+	if d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		d += d * b.Jitter * (rand.Float64()*2 - 1)
+	}
+	return time.Duration(d)
+}
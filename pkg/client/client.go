@@ -0,0 +1,569 @@
+// Package client is a Go SDK for the go-chat gRPC API. It hides the
+// Connect / ReceiveEvents / Message dance behind a Client that delivers
+// typed events, resolves user names and transparently re-subscribes when
+// the server goes away.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrClosed      = errors.New("client closed")
+	ErrUnknownUser = errors.New("unknown user")
+)
+
+// ServerName is the sender name of messages sent by the server itself.
+const ServerName = "server"
+
+// disconnectTimeout bounds how long Close waits for the server to let go
+// of the client.
+const disconnectTimeout = 5 * time.Second
+
+// subscribedHeader is set by the server once it accepted a subscription.
+const subscribedHeader = "gochat-subscribed"
+
+type Option func(*Client)
+
+// WithBackoff sets the reconnect backoff, DefaultBackoff is used otherwise.
+func WithBackoff(b Backoff) Option {
+	return func(c *Client) { c.backoff = b }
+}
+
+// WithDialOptions replaces the default (insecure) grpc dial options.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(c *Client) { c.dialOpts = opts }
+}
+
+// Logger receives what the client can not report through its methods or
+// events, such as lost subscriptions. *log.Logger implements it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// WithLogger sets where the client logs to, nothing is logged otherwise.
+func WithLogger(l Logger) Option {
+	return func(c *Client) { c.log = l }
+}
+
+// WithEventBuffer sets the size of the Events channel.
+func WithEventBuffer(n int) Option {
+	return func(c *Client) { c.events = make(chan Event, n) }
+}
+
+type Client struct {
+	backoff  Backoff
+	dialOpts []grpc.DialOption
+	log      Logger
+
+	conn *grpc.ClientConn
+	api  pb.ChatServerClient
+
+	mu     sync.Mutex
+	name   string
+	id     uuid.UUID
+	ready  chan struct{} // closed while subscribed
+	roster map[uuid.UUID]User
+
+	events chan Event
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Dial connects to the chat server at addr as name and starts receiving
+// events in the background. The returned Client reconnects on its own
+// until Close is called.
+func Dial(ctx context.Context, addr, name string, opts ...Option) (*Client, error) {
+	c := &Client{
+		name:     name,
+		backoff:  DefaultBackoff,
+		dialOpts: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+		log:      log.New(io.Discard, "", 0),
+		ready:    make(chan struct{}),
+		roster:   make(map[uuid.UUID]User),
+		events:   make(chan Event, 100),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	conn, err := grpc.DialContext(ctx, addr, c.dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not dial %s: %w", addr, err)
+	}
+	c.conn = conn
+	c.api = pb.NewChatServerClient(conn)
+
+	if err := c.connect(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	go c.run(runCtx)
+	return c, nil
+}
+
+// ID is the id the server currently knows this client by. It changes
+// when the client had to connect again after a server restart.
+func (c *Client) ID() uuid.UUID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.id
+}
+
+// Name is the name the client goes by, it changes with ChangeName.
+func (c *Client) Name() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.name
+}
+
+// Events returns the channel events are delivered on. It is closed after
+// Close. Not draining it stalls receiving.
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// Send sends text to the user with the given name.
+func (c *Client) Send(ctx context.Context, name, text string) error {
+	u, err := c.Resolve(ctx, name)
+	if err != nil {
+		return err
+	}
+	return c.SendTo(ctx, u.ID, text)
+}
+
+// SendTo sends text to the user with the given id. If the client is
+// reconnecting, it waits until it is subscribed again or ctx is done.
+func (c *Client) SendTo(ctx context.Context, id uuid.UUID, text string) error {
+	self, err := c.waitReady(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = c.api.Message(ctx, &pb.ChatMessage{SenderId: self.String(), RecipientId: id.String(), Text: text})
+	if err != nil {
+		return fmt.Errorf("could not send message: %w", err)
+	}
+	return nil
+}
+
+// SendRoom sends text to a room the client has joined.
+func (c *Client) SendRoom(ctx context.Context, room, text string) error {
+	self, err := c.waitReady(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = c.api.Message(ctx, &pb.ChatMessage{SenderId: self.String(), Room: room, Text: text})
+	if err != nil {
+		return fmt.Errorf("could not send message to %s: %w", room, err)
+	}
+	return nil
+}
+
+// Command runs a server slash command such as "/join #general" and
+// returns its output. room is the room it applies to and may be empty.
+func (c *Client) Command(ctx context.Context, room, line string) (string, error) {
+	self, err := c.waitReady(ctx)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.api.Message(ctx, &pb.ChatMessage{SenderId: self.String(), Room: room, Text: line})
+	if err != nil {
+		return "", fmt.Errorf("command failed: %w", err)
+	}
+	return resp.GetCommandOutput(), nil
+}
+
+// Resolve looks up a connected user by name, with or without a leading
+// @. Names are unique regardless of case.
+func (c *Client) Resolve(ctx context.Context, name string) (User, error) {
+	resp, err := c.api.ResolveUser(ctx, &pb.ResolveUserRequest{Name: name})
+	if status.Code(err) == codes.NotFound {
+		return User{}, fmt.Errorf("%w: %s", ErrUnknownUser, name)
+	}
+	if err != nil {
+		return User{}, fmt.Errorf("could not resolve %s: %w", name, err)
+	}
+	return userFromProto(resp.GetClient())
+}
+
+// ChangeName renames the client, other users keep knowing it by the same
+// id. It fails when someone else goes by name.
+func (c *Client) ChangeName(ctx context.Context, name string) error {
+	self, err := c.waitReady(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := c.api.ChangeName(ctx, &pb.ChangeNameRequest{ClientId: self.String(), Name: name}); err != nil {
+		return fmt.Errorf("could not change name to %s: %w", name, err)
+	}
+	c.mu.Lock()
+	c.name = name
+	c.mu.Unlock()
+	return nil
+}
+
+// Profile asks the server for the profile of the user with the given id.
+func (c *Client) Profile(ctx context.Context, id uuid.UUID) (Profile, error) {
+	resp, err := c.api.GetProfile(ctx, &pb.GetProfileRequest{ClientId: id.String()})
+	if status.Code(err) == codes.NotFound {
+		return Profile{}, fmt.Errorf("%w: %s", ErrUnknownUser, id)
+	}
+	if err != nil {
+		return Profile{}, fmt.Errorf("could not get profile: %w", err)
+	}
+	return profileFromProto(resp.GetProfile()), nil
+}
+
+// UpdateProfile sets the given fields of the client's profile to those of
+// p, all of them when none are given, and returns the resulting profile.
+func (c *Client) UpdateProfile(ctx context.Context, p Profile, fields ...string) (Profile, error) {
+	self, err := c.waitReady(ctx)
+	if err != nil {
+		return Profile{}, err
+	}
+	resp, err := c.api.UpdateProfile(ctx, &pb.UpdateProfileRequest{ClientId: self.String(), Profile: p.proto(), Fields: fields})
+	if err != nil {
+		return Profile{}, fmt.Errorf("could not update profile: %w", err)
+	}
+	return profileFromProto(resp.GetProfile()), nil
+}
+
+// SetStatus sets the client's status, an empty text clears it. A zero
+// expires keeps it until it is changed.
+func (c *Client) SetStatus(ctx context.Context, text string, expires time.Time) error {
+	_, err := c.UpdateProfile(ctx, Profile{Status: text, StatusExpires: expires}, FieldStatus)
+	return err
+}
+
+// Users asks the server for everyone currently connected.
+func (c *Client) Users(ctx context.Context) ([]User, error) {
+	resp, err := c.api.GetConnectedClients(ctx, &pb.ConnectedClientsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("could not get connected clients: %w", err)
+	}
+	users := make([]User, 0, len(resp.GetClients()))
+	for _, cc := range resp.GetClients() {
+		u, err := userFromProto(cc)
+		if err != nil {
+			c.log.Printf("skipping connected client: %v", err)
+			continue
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+// ServerCommand is a slash command the server runs itself.
+type ServerCommand struct {
+	// Name is without the leading slash.
+	Name  string
+	Usage string
+	Help  string
+}
+
+// ServerCommands asks the server for the slash commands it runs itself,
+// messages invoking them never reach other users.
+func (c *Client) ServerCommands(ctx context.Context) ([]ServerCommand, error) {
+	resp, err := c.api.ListCommands(ctx, &pb.ListCommandsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("could not list server commands: %w", err)
+	}
+	cmds := make([]ServerCommand, 0, len(resp.GetCommands()))
+	for _, cmd := range resp.GetCommands() {
+		cmds = append(cmds, ServerCommand{Name: cmd.GetName(), Usage: cmd.GetUsage(), Help: cmd.GetHelp()})
+	}
+	return cmds, nil
+}
+
+// Close stops reconnecting, closes Events and the underlying connection.
+// The server is told to drop the client right away instead of keeping it
+// for a while in case it subscribes again.
+func (c *Client) Close() error {
+	c.cancel()
+	<-c.done
+	if id := c.ID(); id != uuid.Nil {
+		ctx, cancel := context.WithTimeout(context.Background(), disconnectTimeout)
+		// best effort, the server drops it after a while anyway
+		c.api.Disconnect(ctx, &pb.DisconnectRequest{ClientId: id.String()})
+		cancel()
+	}
+	return c.conn.Close()
+}
+
+func (c *Client) connect(ctx context.Context) error {
+	name := c.Name()
+	resp, err := c.api.Connect(ctx, &pb.ConnectRequest{Name: name})
+	if err != nil {
+		return fmt.Errorf("could not connect as %s: %w", name, err)
+	}
+	id, err := uuid.Parse(resp.GetClientId())
+	if err != nil {
+		return fmt.Errorf("server returned invalid client id: %w", err)
+	}
+	c.mu.Lock()
+	c.id = id
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Client) waitReady(ctx context.Context) (uuid.UUID, error) {
+	c.mu.Lock()
+	ready := c.ready
+	c.mu.Unlock()
+	select {
+	case <-ready:
+		return c.ID(), nil
+	case <-c.done:
+		return uuid.Nil, ErrClosed
+	case <-ctx.Done():
+		return uuid.Nil, ctx.Err()
+	}
+}
+
+func (c *Client) setReady(ready bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.ready:
+		if !ready {
+			c.ready = make(chan struct{})
+		}
+	default:
+		if ready {
+			close(c.ready)
+		}
+	}
+}
+
+func (c *Client) run(ctx context.Context) {
+	defer close(c.done)
+	defer close(c.events)
+
+	attempt := 0
+	for sessions := 0; ; sessions++ {
+		subscribed, err := c.session(ctx, sessions > 0)
+		c.setReady(false)
+		if ctx.Err() != nil {
+			return
+		}
+		if subscribed {
+			attempt = 0
+		}
+		if status.Code(err) == codes.NotFound {
+			// server forgot about us (restarted, or we were gone for
+			// longer than it keeps clients), connect again after backing
+			// off as for any other failure
+			c.mu.Lock()
+			c.id = uuid.Nil
+			c.mu.Unlock()
+		}
+		delay := c.backoff.Delay(attempt)
+		c.log.Printf("chat subscription lost: %v, reconnecting in %s", err, delay)
+		attempt++
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// session subscribes to events and receives them until the stream breaks.
+func (c *Client) session(ctx context.Context, reconnect bool) (bool, error) {
+	if c.ID() == uuid.Nil {
+		if err := c.connect(ctx); err != nil {
+			return false, err
+		}
+	}
+	stream, err := c.api.ReceiveEvents(ctx, &pb.ReceiveRequest{ClientId: c.ID().String()})
+	if err != nil {
+		return false, err
+	}
+	// the server sets subscribedHeader once it accepted the
+	// subscription, a stream without it failed and Recv returns the reason
+	md, err := stream.Header()
+	if err != nil {
+		return false, err
+	}
+	if len(md.Get(subscribedHeader)) == 0 {
+		_, err := stream.Recv()
+		return false, err
+	}
+	if err := c.syncRoster(ctx); err != nil {
+		return false, err
+	}
+	c.setReady(true)
+	if reconnect {
+		if !c.emit(ctx, &ReconnectEvent{ID: c.ID()}) {
+			return true, ctx.Err()
+		}
+	}
+
+	for {
+		ev, err := stream.Recv()
+		if err != nil {
+			return true, err
+		}
+		switch e := ev.GetEvent().(type) {
+		case *pb.Event_Message:
+			if !c.emit(ctx, c.messageEvent(e.Message)) {
+				return true, ctx.Err()
+			}
+		case *pb.Event_Presence:
+			u, err := userFromProto(e.Presence.GetClient())
+			if err != nil {
+				c.log.Printf("skipping presence event: %v", err)
+				continue
+			}
+			if e.Presence.GetStatus() == pb.PresenceEvent_UPDATED {
+				c.mu.Lock()
+				c.roster[u.ID] = u
+				c.mu.Unlock()
+				if !c.emit(ctx, &ProfileEvent{User: u}) {
+					return true, ctx.Err()
+				}
+				continue
+			}
+			online := e.Presence.GetStatus() == pb.PresenceEvent_ONLINE
+			c.mu.Lock()
+			_, known := c.roster[u.ID]
+			if online {
+				c.roster[u.ID] = u
+			} else {
+				delete(c.roster, u.ID)
+			}
+			c.mu.Unlock()
+			if known == online {
+				// already seen while syncing the roster
+				continue
+			}
+			if !c.emit(ctx, &PresenceEvent{User: u, Online: online}) {
+				return true, ctx.Err()
+			}
+		case *pb.Event_Renamed:
+			u, err := userFromProto(e.Renamed.GetClient())
+			if err != nil {
+				c.log.Printf("skipping rename event: %v", err)
+				continue
+			}
+			c.mu.Lock()
+			if u.ID == c.id {
+				c.name = u.Name
+			} else {
+				c.roster[u.ID] = u
+			}
+			c.mu.Unlock()
+			if !c.emit(ctx, &RenameEvent{User: u, OldName: e.Renamed.GetOldName()}) {
+				return true, ctx.Err()
+			}
+		case *pb.Event_GoingAway:
+			if !c.emit(ctx, &GoingAwayEvent{Reason: e.GoingAway.GetReason()}) {
+				return true, ctx.Err()
+			}
+		}
+	}
+}
+
+// syncRoster replaces the roster with the server's view, emitting presence,
+// rename and profile events for everyone who came, went or changed while
+// we were not subscribed.
+func (c *Client) syncRoster(ctx context.Context) error {
+	users, err := c.Users(ctx)
+	if err != nil {
+		return err
+	}
+	self := c.ID()
+	current := make(map[uuid.UUID]User, len(users))
+	for _, u := range users {
+		if u.ID != self {
+			current[u.ID] = u
+		}
+	}
+
+	c.mu.Lock()
+	var changes []Event
+	for id, u := range c.roster {
+		if _, ok := current[id]; !ok {
+			changes = append(changes, &PresenceEvent{User: u, Online: false})
+		}
+	}
+	for id, u := range current {
+		if known, ok := c.roster[id]; !ok {
+			changes = append(changes, &PresenceEvent{User: u, Online: true})
+		} else {
+			if known.Name != u.Name {
+				changes = append(changes, &RenameEvent{User: u, OldName: known.Name})
+			}
+			if !known.Profile.equal(u.Profile) {
+				changes = append(changes, &ProfileEvent{User: u})
+			}
+		}
+	}
+	c.roster = current
+	c.mu.Unlock()
+
+	for _, ev := range changes {
+		if !c.emit(ctx, ev) {
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (c *Client) messageEvent(m *pb.ChatMessage) *MessageEvent {
+	ev := &MessageEvent{From: c.lookup(m.GetSenderId()), Room: m.GetRoom(), Text: m.GetText(), Metadata: m.GetMetadata()}
+	if name, ok := m.GetMetadata()["display_name"]; ok && ev.From.ID == uuid.Nil {
+		// posted by an integration on the server's behalf
+		ev.From.Name = name
+	}
+	if m.GetRecipientId() != "" {
+		ev.To = c.lookup(m.GetRecipientId())
+	}
+	return ev
+}
+
+// lookup returns the roster entry for id, or a User without a name.
+func (c *Client) lookup(id string) User {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return User{}
+	}
+	if uid == uuid.Nil {
+		return User{ID: uid, Name: ServerName}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if uid == c.id {
+		return User{ID: uid, Name: c.name}
+	}
+	if u, ok := c.roster[uid]; ok {
+		return u
+	}
+	return User{ID: uid}
+}
+
+func (c *Client) emit(ctx context.Context, ev Event) bool {
+	select {
+	case c.events <- ev:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,324 @@
+package client
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeServer is just enough of a chat server for the client to connect,
+// subscribe and rename. Tests drive it through the streams it accepted.
+type fakeServer struct {
+	pb.UnimplementedChatServerServer
+
+	mu           sync.Mutex
+	clients      map[string]string // id to name
+	others       []*pb.ConnectedClientsResponse_ConnectedClient
+	connects     int
+	disconnected []string
+
+	streams chan *fakeStream
+}
+
+// fakeStream is an accepted ReceiveEvents call, it ends with the error
+// sent on end.
+type fakeStream struct {
+	id     string
+	events chan *pb.Event
+	end    chan error
+}
+
+func (f *fakeServer) Connect(ctx context.Context, in *pb.ConnectRequest) (*pb.ConnectResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := uuid.New().String()
+	f.clients[id] = in.GetName()
+	f.connects++
+	return &pb.ConnectResponse{ClientId: id}, nil
+}
+
+func (f *fakeServer) Disconnect(ctx context.Context, in *pb.DisconnectRequest) (*pb.DisconnectResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.clients, in.GetClientId())
+	f.disconnected = append(f.disconnected, in.GetClientId())
+	return &pb.DisconnectResponse{}, nil
+}
+
+func (f *fakeServer) ChangeName(ctx context.Context, in *pb.ChangeNameRequest) (*pb.ChangeNameResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	old, ok := f.clients[in.GetClientId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "no such client")
+	}
+	f.clients[in.GetClientId()] = in.GetName()
+	return &pb.ChangeNameResponse{OldName: old}, nil
+}
+
+func (f *fakeServer) GetConnectedClients(ctx context.Context, in *pb.ConnectedClientsRequest) (*pb.ConnectedClientsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := &pb.ConnectedClientsResponse{Clients: append([]*pb.ConnectedClientsResponse_ConnectedClient(nil), f.others...)}
+	for id, name := range f.clients {
+		resp.Clients = append(resp.Clients, &pb.ConnectedClientsResponse_ConnectedClient{Id: id, Name: name})
+	}
+	return resp, nil
+}
+
+func (f *fakeServer) ReceiveEvents(in *pb.ReceiveRequest, stream pb.ChatServer_ReceiveEventsServer) error {
+	f.mu.Lock()
+	_, ok := f.clients[in.GetClientId()]
+	f.mu.Unlock()
+	if !ok {
+		return status.Errorf(codes.NotFound, "client %s not found", in.GetClientId())
+	}
+	if err := stream.SendHeader(metadata.Pairs(subscribedHeader, "true")); err != nil {
+		return err
+	}
+	s := &fakeStream{id: in.GetClientId(), events: make(chan *pb.Event), end: make(chan error)}
+	f.streams <- s
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case err := <-s.end:
+			return err
+		case ev := <-s.events:
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// forget drops the client with the given id, as a restarted server would.
+func (f *fakeServer) forget(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.clients, id)
+}
+
+func (f *fakeServer) setOthers(others ...*pb.ConnectedClientsResponse_ConnectedClient) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.others = others
+}
+
+func (f *fakeServer) stream(t *testing.T) *fakeStream {
+	t.Helper()
+	select {
+	case s := <-f.streams:
+		return s
+	case <-time.After(5 * time.Second):
+		t.Fatal("client did not subscribe")
+		return nil
+	}
+}
+
+func (s *fakeStream) send(t *testing.T, ev *pb.Event) {
+	t.Helper()
+	select {
+	case s.events <- ev:
+	case <-time.After(5 * time.Second):
+		t.Fatal("client did not receive event")
+	}
+}
+
+// startFake serves a fakeServer over bufconn, with others connected,
+// and dials it as name.
+func startFake(t *testing.T, name string, others ...*pb.ConnectedClientsResponse_ConnectedClient) (*fakeServer, *Client) {
+	t.Helper()
+	f := &fakeServer{clients: make(map[string]string), others: others, streams: make(chan *fakeStream, 10)}
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	pb.RegisterChatServerServer(srv, f)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := Dial(ctx, "bufnet", name,
+		WithDialOptions(grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials())),
+		WithBackoff(Backoff{Initial: time.Millisecond, Max: 10 * time.Millisecond, Factor: 2}),
+	)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return f, c
+}
+
+func nextEvent(t *testing.T, c *Client) Event {
+	t.Helper()
+	select {
+	case ev, ok := <-c.Events():
+		if !ok {
+			t.Fatal("events closed")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+		return nil
+	}
+}
+
+func connected(id uuid.UUID, name string) *pb.ConnectedClientsResponse_ConnectedClient {
+	return &pb.ConnectedClientsResponse_ConnectedClient{Id: id.String(), Name: name}
+}
+
+func presence(id uuid.UUID, name string, st pb.PresenceEvent_Status) *pb.Event {
+	return &pb.Event{Event: &pb.Event_Presence{Presence: &pb.PresenceEvent{Client: connected(id, name), Status: st}}}
+}
+
+func message(from uuid.UUID, text string) *pb.Event {
+	return &pb.Event{Event: &pb.Event_Message{Message: &pb.ChatMessage{SenderId: from.String(), Text: text}}}
+}
+
+func renamed(id uuid.UUID, name, old string) *pb.Event {
+	return &pb.Event{Event: &pb.Event_Renamed{Renamed: &pb.RenameEvent{Client: connected(id, name), OldName: old}}}
+}
+
+func TestReconnectResumes(t *testing.T) {
+	f, c := startFake(t, "alice")
+	id := c.ID()
+	s := f.stream(t)
+
+	s.end <- status.Error(codes.Unavailable, "connection reset")
+	if s := f.stream(t); s.id != id.String() {
+		t.Fatalf("subscribed again as %s, want %s", s.id, id)
+	}
+	ev, ok := nextEvent(t, c).(*ReconnectEvent)
+	if !ok || ev.ID != id {
+		t.Fatalf("got %#v, want a ReconnectEvent for %s", ev, id)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.connects != 1 {
+		t.Errorf("connected %d times, want 1", f.connects)
+	}
+}
+
+func TestReconnectAfterNotFound(t *testing.T) {
+	f, c := startFake(t, "alice")
+	old := c.ID()
+	s := f.stream(t)
+
+	f.forget(old.String())
+	s.end <- status.Error(codes.Unavailable, "server restarting")
+	s = f.stream(t)
+	if s.id == old.String() {
+		t.Fatalf("subscribed with the forgotten id %s", old)
+	}
+	ev, ok := nextEvent(t, c).(*ReconnectEvent)
+	if !ok || ev.ID.String() != s.id {
+		t.Fatalf("got %#v, want a ReconnectEvent for %s", ev, s.id)
+	}
+	if c.ID() != ev.ID {
+		t.Errorf("ID() = %s, want %s", c.ID(), ev.ID)
+	}
+	if c.Name() != "alice" {
+		t.Errorf("Name() = %q, want alice", c.Name())
+	}
+}
+
+func TestCloseDisconnects(t *testing.T) {
+	f, c := startFake(t, "alice")
+	id := c.ID()
+	f.stream(t)
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.disconnected) != 1 || f.disconnected[0] != id.String() {
+		t.Errorf("disconnected %v, want [%s]", f.disconnected, id)
+	}
+}
+
+func TestPresence(t *testing.T) {
+	bob, carol := uuid.New(), uuid.New()
+	f, c := startFake(t, "alice", connected(bob, "bob"))
+	s := f.stream(t)
+
+	if ev, ok := nextEvent(t, c).(*PresenceEvent); !ok || ev.User.ID != bob || !ev.Online {
+		t.Fatalf("got %#v, want bob online from the roster", ev)
+	}
+	s.send(t, presence(carol, "carol", pb.PresenceEvent_ONLINE))
+	if ev, ok := nextEvent(t, c).(*PresenceEvent); !ok || ev.User.Name != "carol" || !ev.Online {
+		t.Fatalf("got %#v, want carol online", ev)
+	}
+	// already known, not reported again
+	s.send(t, presence(bob, "bob", pb.PresenceEvent_ONLINE))
+	s.send(t, presence(carol, "carol", pb.PresenceEvent_OFFLINE))
+	if ev, ok := nextEvent(t, c).(*PresenceEvent); !ok || ev.User.Name != "carol" || ev.Online {
+		t.Fatalf("got %#v, want carol offline", ev)
+	}
+
+	updated := connected(bob, "bob")
+	updated.Profile = &pb.Profile{Status: "at lunch"}
+	s.send(t, &pb.Event{Event: &pb.Event_Presence{Presence: &pb.PresenceEvent{Client: updated, Status: pb.PresenceEvent_UPDATED}}})
+	if ev, ok := nextEvent(t, c).(*ProfileEvent); !ok || ev.User.Profile.Status != "at lunch" {
+		t.Fatalf("got %#v, want bob's new status", ev)
+	}
+	s.send(t, message(bob, "hi"))
+	if ev, ok := nextEvent(t, c).(*MessageEvent); !ok || ev.From.Name != "bob" || ev.From.Profile.Status != "at lunch" {
+		t.Fatalf("got %#v, want a message from bob with his profile", ev)
+	}
+}
+
+func TestRename(t *testing.T) {
+	bob := uuid.New()
+	f, c := startFake(t, "alice", connected(bob, "bob"))
+	s := f.stream(t)
+	nextEvent(t, c) // bob online
+
+	s.send(t, renamed(bob, "robert", "bob"))
+	if ev, ok := nextEvent(t, c).(*RenameEvent); !ok || ev.User.Name != "robert" || ev.OldName != "bob" {
+		t.Fatalf("got %#v, want bob renamed to robert", ev)
+	}
+	s.send(t, message(bob, "hi"))
+	if ev, ok := nextEvent(t, c).(*MessageEvent); !ok || ev.From.Name != "robert" {
+		t.Fatalf("got %#v, want a message from robert", ev)
+	}
+
+	// renamed by the server, e.g. through /nick
+	s.send(t, renamed(c.ID(), "alicia", "alice"))
+	if ev, ok := nextEvent(t, c).(*RenameEvent); !ok || ev.User.ID != c.ID() {
+		t.Fatalf("got %#v, want our own rename", ev)
+	}
+	if c.Name() != "alicia" {
+		t.Errorf("Name() = %q after rename event, want alicia", c.Name())
+	}
+	if err := c.ChangeName(context.Background(), "al"); err != nil {
+		t.Fatalf("ChangeName: %v", err)
+	}
+	if c.Name() != "al" {
+		t.Errorf("Name() = %q after ChangeName, want al", c.Name())
+	}
+
+	// renamed while we were not subscribed
+	f.setOthers(connected(bob, "rob"))
+	s.end <- status.Error(codes.Unavailable, "connection reset")
+	f.stream(t)
+	if ev, ok := nextEvent(t, c).(*RenameEvent); !ok || ev.User.Name != "rob" || ev.OldName != "robert" {
+		t.Fatalf("got %#v, want robert renamed to rob from the roster", ev)
+	}
+	if _, ok := nextEvent(t, c).(*ReconnectEvent); !ok {
+		t.Fatal("want a ReconnectEvent after the roster changes")
+	}
+}
@@ -0,0 +1,153 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/wmolicki/go-chat/pkg/message/proto"
+)
+
+// User is a client connected to the chat server.
+type User struct {
+	ID      uuid.UUID
+	Name    string
+	Profile Profile
+}
+
+// Profile is what a user tells others about itself, fields are empty when
+// not set.
+type Profile struct {
+	DisplayName string
+	Pronouns    string
+	Bio         string
+	// IANA name, e.g. Europe/Warsaw
+	TimeZone string
+	Avatar   Attachment
+	Status   string
+	// zero when the status does not expire
+	StatusExpires time.Time
+}
+
+// Attachment is a file stored elsewhere, e.g. an avatar image.
+type Attachment struct {
+	URL         string
+	ContentType string
+}
+
+// Fields of Profile, for UpdateProfile.
+const (
+	FieldDisplayName = "display_name"
+	FieldPronouns    = "pronouns"
+	FieldBio         = "bio"
+	FieldTimeZone    = "time_zone"
+	FieldAvatar      = "avatar"
+	// sets StatusExpires too
+	FieldStatus = "status"
+)
+
+func (u User) String() string {
+	return fmt.Sprintf("%s (%s)", u.Name, u.ID)
+}
+
+// Event is delivered on Client.Events. It is one of *MessageEvent,
+// *PresenceEvent, *RenameEvent, *ProfileEvent, *GoingAwayEvent or
+// *ReconnectEvent.
+type Event interface {
+	isEvent()
+}
+
+// MessageEvent is a chat message sent to this client, or to a room it
+// is a member of, in which case Room is set and To is empty.
+type MessageEvent struct {
+	From User
+	To   User
+	Room string
+	Text string
+	// Metadata is attached by the server, e.g. "links" and "mentions".
+	Metadata map[string]string
+}
+
+// PresenceEvent reports a user going online or offline.
+type PresenceEvent struct {
+	User   User
+	Online bool
+}
+
+// RenameEvent reports a user, possibly this client, changing its name.
+// Its ID stays the same.
+type RenameEvent struct {
+	User    User
+	OldName string
+}
+
+// ProfileEvent reports a user changing its profile, or its status
+// expiring.
+type ProfileEvent struct {
+	User User
+}
+
+// GoingAwayEvent is emitted when the server is about to shut down. The
+// client reconnects on its own, a ReconnectEvent follows once it did.
+type GoingAwayEvent struct {
+	Reason string
+}
+
+// ReconnectEvent is emitted after the client re-established its
+// subscription. ID changes when the server no longer knew the old one.
+type ReconnectEvent struct {
+	ID uuid.UUID
+}
+
+func (*MessageEvent) isEvent()   {}
+func (*PresenceEvent) isEvent()  {}
+func (*RenameEvent) isEvent()    {}
+func (*ProfileEvent) isEvent()   {}
+func (*GoingAwayEvent) isEvent() {}
+func (*ReconnectEvent) isEvent() {}
+
+func userFromProto(c *pb.ConnectedClientsResponse_ConnectedClient) (User, error) {
+	id, err := uuid.Parse(c.GetId())
+	if err != nil {
+		return User{}, fmt.Errorf("invalid user id %q: %w", c.GetId(), err)
+	}
+	return User{ID: id, Name: c.GetName(), Profile: profileFromProto(c.GetProfile())}, nil
+}
+
+func profileFromProto(p *pb.Profile) Profile {
+	profile := Profile{
+		DisplayName: p.GetDisplayName(),
+		Pronouns:    p.GetPronouns(),
+		Bio:         p.GetBio(),
+		TimeZone:    p.GetTimeZone(),
+		Avatar:      Attachment{URL: p.GetAvatar().GetUrl(), ContentType: p.GetAvatar().GetContentType()},
+		Status:      p.GetStatus(),
+	}
+	if p.GetStatusExpiresAt() != 0 {
+		profile.StatusExpires = time.Unix(p.GetStatusExpiresAt(), 0)
+	}
+	return profile
+}
+
+func (p Profile) proto() *pb.Profile {
+	profile := &pb.Profile{
+		DisplayName: p.DisplayName,
+		Pronouns:    p.Pronouns,
+		Bio:         p.Bio,
+		TimeZone:    p.TimeZone,
+		Status:      p.Status,
+	}
+	if p.Avatar != (Attachment{}) {
+		profile.Avatar = &pb.Attachment{Url: p.Avatar.URL, ContentType: p.Avatar.ContentType}
+	}
+	if !p.StatusExpires.IsZero() {
+		profile.StatusExpiresAt = p.StatusExpires.Unix()
+	}
+	return profile
+}
+
+func (p Profile) equal(o Profile) bool {
+	return p.DisplayName == o.DisplayName && p.Pronouns == o.Pronouns && p.Bio == o.Bio &&
+		p.TimeZone == o.TimeZone && p.Avatar == o.Avatar && p.Status == o.Status &&
+		p.StatusExpires.Equal(o.StatusExpires)
+}
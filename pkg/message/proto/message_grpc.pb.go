@@ -20,8 +20,24 @@ const _ = grpc.SupportPackageIsVersion7
 type ChatServerClient interface {
 	GetConnectedClients(ctx context.Context, in *ConnectedClientsRequest, opts ...grpc.CallOption) (*ConnectedClientsResponse, error)
 	Connect(ctx context.Context, in *ConnectRequest, opts ...grpc.CallOption) (*ConnectResponse, error)
+	// Disconnect ends a client right away, instead of once it did not
+	// subscribe again for a while after its stream ended.
+	Disconnect(ctx context.Context, in *DisconnectRequest, opts ...grpc.CallOption) (*DisconnectResponse, error)
 	Message(ctx context.Context, in *ChatMessage, opts ...grpc.CallOption) (*MessageResponse, error)
 	ReceiveMessages(ctx context.Context, in *ReceiveRequest, opts ...grpc.CallOption) (ChatServer_ReceiveMessagesClient, error)
+	ReceiveEvents(ctx context.Context, in *ReceiveRequest, opts ...grpc.CallOption) (ChatServer_ReceiveEventsClient, error)
+	// ResolveUser looks a client up by name, names are unique regardless
+	// of case.
+	ResolveUser(ctx context.Context, in *ResolveUserRequest, opts ...grpc.CallOption) (*ResolveUserResponse, error)
+	// ChangeName renames a client, everyone gets a RenameEvent.
+	ChangeName(ctx context.Context, in *ChangeNameRequest, opts ...grpc.CallOption) (*ChangeNameResponse, error)
+	GetProfile(ctx context.Context, in *GetProfileRequest, opts ...grpc.CallOption) (*GetProfileResponse, error)
+	// UpdateProfile sets fields of a client's profile, everyone gets an
+	// UPDATED PresenceEvent.
+	UpdateProfile(ctx context.Context, in *UpdateProfileRequest, opts ...grpc.CallOption) (*UpdateProfileResponse, error)
+	// ListCommands lists the slash commands the server runs itself,
+	// messages invoking them never reach other clients.
+	ListCommands(ctx context.Context, in *ListCommandsRequest, opts ...grpc.CallOption) (*ListCommandsResponse, error)
 }
 
 type chatServerClient struct {
@@ -50,6 +66,15 @@ func (c *chatServerClient) Connect(ctx context.Context, in *ConnectRequest, opts
 	return out, nil
 }
 
+func (c *chatServerClient) Disconnect(ctx context.Context, in *DisconnectRequest, opts ...grpc.CallOption) (*DisconnectResponse, error) {
+	out := new(DisconnectResponse)
+	err := c.cc.Invoke(ctx, "/msg.ChatServer/Disconnect", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *chatServerClient) Message(ctx context.Context, in *ChatMessage, opts ...grpc.CallOption) (*MessageResponse, error) {
 	out := new(MessageResponse)
 	err := c.cc.Invoke(ctx, "/msg.ChatServer/Message", in, out, opts...)
@@ -91,14 +116,107 @@ func (x *chatServerReceiveMessagesClient) Recv() (*ChatMessage, error) {
 	return m, nil
 }
 
+func (c *chatServerClient) ReceiveEvents(ctx context.Context, in *ReceiveRequest, opts ...grpc.CallOption) (ChatServer_ReceiveEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ChatServer_ServiceDesc.Streams[1], "/msg.ChatServer/ReceiveEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &chatServerReceiveEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ChatServer_ReceiveEventsClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type chatServerReceiveEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *chatServerReceiveEventsClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *chatServerClient) ResolveUser(ctx context.Context, in *ResolveUserRequest, opts ...grpc.CallOption) (*ResolveUserResponse, error) {
+	out := new(ResolveUserResponse)
+	err := c.cc.Invoke(ctx, "/msg.ChatServer/ResolveUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServerClient) ChangeName(ctx context.Context, in *ChangeNameRequest, opts ...grpc.CallOption) (*ChangeNameResponse, error) {
+	out := new(ChangeNameResponse)
+	err := c.cc.Invoke(ctx, "/msg.ChatServer/ChangeName", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServerClient) GetProfile(ctx context.Context, in *GetProfileRequest, opts ...grpc.CallOption) (*GetProfileResponse, error) {
+	out := new(GetProfileResponse)
+	err := c.cc.Invoke(ctx, "/msg.ChatServer/GetProfile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServerClient) UpdateProfile(ctx context.Context, in *UpdateProfileRequest, opts ...grpc.CallOption) (*UpdateProfileResponse, error) {
+	out := new(UpdateProfileResponse)
+	err := c.cc.Invoke(ctx, "/msg.ChatServer/UpdateProfile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServerClient) ListCommands(ctx context.Context, in *ListCommandsRequest, opts ...grpc.CallOption) (*ListCommandsResponse, error) {
+	out := new(ListCommandsResponse)
+	err := c.cc.Invoke(ctx, "/msg.ChatServer/ListCommands", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ChatServerServer is the server API for ChatServer service.
 // All implementations must embed UnimplementedChatServerServer
 // for forward compatibility
 type ChatServerServer interface {
 	GetConnectedClients(context.Context, *ConnectedClientsRequest) (*ConnectedClientsResponse, error)
 	Connect(context.Context, *ConnectRequest) (*ConnectResponse, error)
+	// Disconnect ends a client right away, instead of once it did not
+	// subscribe again for a while after its stream ended.
+	Disconnect(context.Context, *DisconnectRequest) (*DisconnectResponse, error)
 	Message(context.Context, *ChatMessage) (*MessageResponse, error)
 	ReceiveMessages(*ReceiveRequest, ChatServer_ReceiveMessagesServer) error
+	ReceiveEvents(*ReceiveRequest, ChatServer_ReceiveEventsServer) error
+	// ResolveUser looks a client up by name, names are unique regardless
+	// of case.
+	ResolveUser(context.Context, *ResolveUserRequest) (*ResolveUserResponse, error)
+	// ChangeName renames a client, everyone gets a RenameEvent.
+	ChangeName(context.Context, *ChangeNameRequest) (*ChangeNameResponse, error)
+	GetProfile(context.Context, *GetProfileRequest) (*GetProfileResponse, error)
+	// UpdateProfile sets fields of a client's profile, everyone gets an
+	// UPDATED PresenceEvent.
+	UpdateProfile(context.Context, *UpdateProfileRequest) (*UpdateProfileResponse, error)
+	// ListCommands lists the slash commands the server runs itself,
+	// messages invoking them never reach other clients.
+	ListCommands(context.Context, *ListCommandsRequest) (*ListCommandsResponse, error)
 	mustEmbedUnimplementedChatServerServer()
 }
 
@@ -112,12 +230,33 @@ func (UnimplementedChatServerServer) GetConnectedClients(context.Context, *Conne
 func (UnimplementedChatServerServer) Connect(context.Context, *ConnectRequest) (*ConnectResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Connect not implemented")
 }
+func (UnimplementedChatServerServer) Disconnect(context.Context, *DisconnectRequest) (*DisconnectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Disconnect not implemented")
+}
 func (UnimplementedChatServerServer) Message(context.Context, *ChatMessage) (*MessageResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Message not implemented")
 }
 func (UnimplementedChatServerServer) ReceiveMessages(*ReceiveRequest, ChatServer_ReceiveMessagesServer) error {
 	return status.Errorf(codes.Unimplemented, "method ReceiveMessages not implemented")
 }
+func (UnimplementedChatServerServer) ReceiveEvents(*ReceiveRequest, ChatServer_ReceiveEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ReceiveEvents not implemented")
+}
+func (UnimplementedChatServerServer) ResolveUser(context.Context, *ResolveUserRequest) (*ResolveUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResolveUser not implemented")
+}
+func (UnimplementedChatServerServer) ChangeName(context.Context, *ChangeNameRequest) (*ChangeNameResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangeName not implemented")
+}
+func (UnimplementedChatServerServer) GetProfile(context.Context, *GetProfileRequest) (*GetProfileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProfile not implemented")
+}
+func (UnimplementedChatServerServer) UpdateProfile(context.Context, *UpdateProfileRequest) (*UpdateProfileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateProfile not implemented")
+}
+func (UnimplementedChatServerServer) ListCommands(context.Context, *ListCommandsRequest) (*ListCommandsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCommands not implemented")
+}
 func (UnimplementedChatServerServer) mustEmbedUnimplementedChatServerServer() {}
 
 // UnsafeChatServerServer may be embedded to opt out of forward compatibility for this service.
@@ -167,6 +306,24 @@ func _ChatServer_Connect_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ChatServer_Disconnect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisconnectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServerServer).Disconnect(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.ChatServer/Disconnect",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServerServer).Disconnect(ctx, req.(*DisconnectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ChatServer_Message_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ChatMessage)
 	if err := dec(in); err != nil {
@@ -206,6 +363,117 @@ func (x *chatServerReceiveMessagesServer) Send(m *ChatMessage) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _ChatServer_ReceiveEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReceiveRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ChatServerServer).ReceiveEvents(m, &chatServerReceiveEventsServer{stream})
+}
+
+type ChatServer_ReceiveEventsServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type chatServerReceiveEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *chatServerReceiveEventsServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ChatServer_ResolveUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServerServer).ResolveUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.ChatServer/ResolveUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServerServer).ResolveUser(ctx, req.(*ResolveUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatServer_ChangeName_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeNameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServerServer).ChangeName(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.ChatServer/ChangeName",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServerServer).ChangeName(ctx, req.(*ChangeNameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatServer_GetProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServerServer).GetProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.ChatServer/GetProfile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServerServer).GetProfile(ctx, req.(*GetProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatServer_UpdateProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServerServer).UpdateProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.ChatServer/UpdateProfile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServerServer).UpdateProfile(ctx, req.(*UpdateProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatServer_ListCommands_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCommandsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServerServer).ListCommands(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.ChatServer/ListCommands",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServerServer).ListCommands(ctx, req.(*ListCommandsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ChatServer_ServiceDesc is the grpc.ServiceDesc for ChatServer service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -221,10 +489,34 @@ var ChatServer_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Connect",
 			Handler:    _ChatServer_Connect_Handler,
 		},
+		{
+			MethodName: "Disconnect",
+			Handler:    _ChatServer_Disconnect_Handler,
+		},
 		{
 			MethodName: "Message",
 			Handler:    _ChatServer_Message_Handler,
 		},
+		{
+			MethodName: "ResolveUser",
+			Handler:    _ChatServer_ResolveUser_Handler,
+		},
+		{
+			MethodName: "ChangeName",
+			Handler:    _ChatServer_ChangeName_Handler,
+		},
+		{
+			MethodName: "GetProfile",
+			Handler:    _ChatServer_GetProfile_Handler,
+		},
+		{
+			MethodName: "UpdateProfile",
+			Handler:    _ChatServer_UpdateProfile_Handler,
+		},
+		{
+			MethodName: "ListCommands",
+			Handler:    _ChatServer_ListCommands_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -232,6 +524,531 @@ var ChatServer_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _ChatServer_ReceiveMessages_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "ReceiveEvents",
+			Handler:       _ChatServer_ReceiveEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pkg/message/proto/message.proto",
+}
+
+// FederationClient is the client API for Federation service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type FederationClient interface {
+	// Relay delivers a direct message from a user of the calling server.
+	Relay(ctx context.Context, in *FederatedMessage, opts ...grpc.CallOption) (*RelayResponse, error)
+	// Presence sends ONLINE for every local user, then every change.
+	Presence(ctx context.Context, in *PresenceRequest, opts ...grpc.CallOption) (Federation_PresenceClient, error)
+}
+
+type federationClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFederationClient(cc grpc.ClientConnInterface) FederationClient {
+	return &federationClient{cc}
+}
+
+func (c *federationClient) Relay(ctx context.Context, in *FederatedMessage, opts ...grpc.CallOption) (*RelayResponse, error) {
+	out := new(RelayResponse)
+	err := c.cc.Invoke(ctx, "/msg.Federation/Relay", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *federationClient) Presence(ctx context.Context, in *PresenceRequest, opts ...grpc.CallOption) (Federation_PresenceClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Federation_ServiceDesc.Streams[0], "/msg.Federation/Presence", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &federationPresenceClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Federation_PresenceClient interface {
+	Recv() (*PresenceEvent, error)
+	grpc.ClientStream
+}
+
+type federationPresenceClient struct {
+	grpc.ClientStream
+}
+
+func (x *federationPresenceClient) Recv() (*PresenceEvent, error) {
+	m := new(PresenceEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FederationServer is the server API for Federation service.
+// All implementations must embed UnimplementedFederationServer
+// for forward compatibility
+type FederationServer interface {
+	// Relay delivers a direct message from a user of the calling server.
+	Relay(context.Context, *FederatedMessage) (*RelayResponse, error)
+	// Presence sends ONLINE for every local user, then every change.
+	Presence(*PresenceRequest, Federation_PresenceServer) error
+	mustEmbedUnimplementedFederationServer()
+}
+
+// UnimplementedFederationServer must be embedded to have forward compatible implementations.
+type UnimplementedFederationServer struct {
+}
+
+func (UnimplementedFederationServer) Relay(context.Context, *FederatedMessage) (*RelayResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Relay not implemented")
+}
+func (UnimplementedFederationServer) Presence(*PresenceRequest, Federation_PresenceServer) error {
+	return status.Errorf(codes.Unimplemented, "method Presence not implemented")
+}
+func (UnimplementedFederationServer) mustEmbedUnimplementedFederationServer() {}
+
+// UnsafeFederationServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FederationServer will
+// result in compilation errors.
+type UnsafeFederationServer interface {
+	mustEmbedUnimplementedFederationServer()
+}
+
+func RegisterFederationServer(s grpc.ServiceRegistrar, srv FederationServer) {
+	s.RegisterService(&Federation_ServiceDesc, srv)
+}
+
+func _Federation_Relay_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FederatedMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FederationServer).Relay(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Federation/Relay",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FederationServer).Relay(ctx, req.(*FederatedMessage))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Federation_Presence_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PresenceRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FederationServer).Presence(m, &federationPresenceServer{stream})
+}
+
+type Federation_PresenceServer interface {
+	Send(*PresenceEvent) error
+	grpc.ServerStream
+}
+
+type federationPresenceServer struct {
+	grpc.ServerStream
+}
+
+func (x *federationPresenceServer) Send(m *PresenceEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Federation_ServiceDesc is the grpc.ServiceDesc for Federation service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Federation_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "msg.Federation",
+	HandlerType: (*FederationServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Relay",
+			Handler:    _Federation_Relay_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Presence",
+			Handler:       _Federation_Presence_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pkg/message/proto/message.proto",
+}
+
+// BrokerClient is the client API for Broker service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BrokerClient interface {
+	Attach(ctx context.Context, opts ...grpc.CallOption) (Broker_AttachClient, error)
+}
+
+type brokerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBrokerClient(cc grpc.ClientConnInterface) BrokerClient {
+	return &brokerClient{cc}
+}
+
+func (c *brokerClient) Attach(ctx context.Context, opts ...grpc.CallOption) (Broker_AttachClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Broker_ServiceDesc.Streams[0], "/msg.Broker/Attach", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &brokerAttachClient{stream}
+	return x, nil
+}
+
+type Broker_AttachClient interface {
+	Send(*BrokerFrame) error
+	Recv() (*BrokerEnvelope, error)
+	grpc.ClientStream
+}
+
+type brokerAttachClient struct {
+	grpc.ClientStream
+}
+
+func (x *brokerAttachClient) Send(m *BrokerFrame) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *brokerAttachClient) Recv() (*BrokerEnvelope, error) {
+	m := new(BrokerEnvelope)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// BrokerServer is the server API for Broker service.
+// All implementations must embed UnimplementedBrokerServer
+// for forward compatibility
+type BrokerServer interface {
+	Attach(Broker_AttachServer) error
+	mustEmbedUnimplementedBrokerServer()
+}
+
+// UnimplementedBrokerServer must be embedded to have forward compatible implementations.
+type UnimplementedBrokerServer struct {
+}
+
+func (UnimplementedBrokerServer) Attach(Broker_AttachServer) error {
+	return status.Errorf(codes.Unimplemented, "method Attach not implemented")
+}
+func (UnimplementedBrokerServer) mustEmbedUnimplementedBrokerServer() {}
+
+// UnsafeBrokerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BrokerServer will
+// result in compilation errors.
+type UnsafeBrokerServer interface {
+	mustEmbedUnimplementedBrokerServer()
+}
+
+func RegisterBrokerServer(s grpc.ServiceRegistrar, srv BrokerServer) {
+	s.RegisterService(&Broker_ServiceDesc, srv)
+}
+
+func _Broker_Attach_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(BrokerServer).Attach(&brokerAttachServer{stream})
+}
+
+type Broker_AttachServer interface {
+	Send(*BrokerEnvelope) error
+	Recv() (*BrokerFrame, error)
+	grpc.ServerStream
+}
+
+type brokerAttachServer struct {
+	grpc.ServerStream
+}
+
+func (x *brokerAttachServer) Send(m *BrokerEnvelope) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *brokerAttachServer) Recv() (*BrokerFrame, error) {
+	m := new(BrokerFrame)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Broker_ServiceDesc is the grpc.ServiceDesc for Broker service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Broker_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "msg.Broker",
+	HandlerType: (*BrokerServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Attach",
+			Handler:       _Broker_Attach_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "pkg/message/proto/message.proto",
+}
+
+// RaftClient is the client API for Raft service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RaftClient interface {
+	Join(ctx context.Context, in *JoinRequest, opts ...grpc.CallOption) (*JoinResponse, error)
+	Leave(ctx context.Context, in *LeaveRequest, opts ...grpc.CallOption) (*LeaveResponse, error)
+	// Propose appends a client, room or member change to the raft log.
+	Propose(ctx context.Context, in *BrokerEnvelope, opts ...grpc.CallOption) (*ProposeResponse, error)
+}
+
+type raftClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRaftClient(cc grpc.ClientConnInterface) RaftClient {
+	return &raftClient{cc}
+}
+
+func (c *raftClient) Join(ctx context.Context, in *JoinRequest, opts ...grpc.CallOption) (*JoinResponse, error) {
+	out := new(JoinResponse)
+	err := c.cc.Invoke(ctx, "/msg.Raft/Join", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *raftClient) Leave(ctx context.Context, in *LeaveRequest, opts ...grpc.CallOption) (*LeaveResponse, error) {
+	out := new(LeaveResponse)
+	err := c.cc.Invoke(ctx, "/msg.Raft/Leave", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *raftClient) Propose(ctx context.Context, in *BrokerEnvelope, opts ...grpc.CallOption) (*ProposeResponse, error) {
+	out := new(ProposeResponse)
+	err := c.cc.Invoke(ctx, "/msg.Raft/Propose", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RaftServer is the server API for Raft service.
+// All implementations must embed UnimplementedRaftServer
+// for forward compatibility
+type RaftServer interface {
+	Join(context.Context, *JoinRequest) (*JoinResponse, error)
+	Leave(context.Context, *LeaveRequest) (*LeaveResponse, error)
+	// Propose appends a client, room or member change to the raft log.
+	Propose(context.Context, *BrokerEnvelope) (*ProposeResponse, error)
+	mustEmbedUnimplementedRaftServer()
+}
+
+// UnimplementedRaftServer must be embedded to have forward compatible implementations.
+type UnimplementedRaftServer struct {
+}
+
+func (UnimplementedRaftServer) Join(context.Context, *JoinRequest) (*JoinResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Join not implemented")
+}
+func (UnimplementedRaftServer) Leave(context.Context, *LeaveRequest) (*LeaveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Leave not implemented")
+}
+func (UnimplementedRaftServer) Propose(context.Context, *BrokerEnvelope) (*ProposeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Propose not implemented")
+}
+func (UnimplementedRaftServer) mustEmbedUnimplementedRaftServer() {}
+
+// UnsafeRaftServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RaftServer will
+// result in compilation errors.
+type UnsafeRaftServer interface {
+	mustEmbedUnimplementedRaftServer()
+}
+
+func RegisterRaftServer(s grpc.ServiceRegistrar, srv RaftServer) {
+	s.RegisterService(&Raft_ServiceDesc, srv)
+}
+
+func _Raft_Join_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JoinRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftServer).Join(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Raft/Join",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftServer).Join(ctx, req.(*JoinRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Raft_Leave_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LeaveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftServer).Leave(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Raft/Leave",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftServer).Leave(ctx, req.(*LeaveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Raft_Propose_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BrokerEnvelope)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaftServer).Propose(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Raft/Propose",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaftServer).Propose(ctx, req.(*BrokerEnvelope))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Raft_ServiceDesc is the grpc.ServiceDesc for Raft service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Raft_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "msg.Raft",
+	HandlerType: (*RaftServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Join",
+			Handler:    _Raft_Join_Handler,
+		},
+		{
+			MethodName: "Leave",
+			Handler:    _Raft_Leave_Handler,
+		},
+		{
+			MethodName: "Propose",
+			Handler:    _Raft_Propose_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "pkg/message/proto/message.proto",
+}
+
+// AdminClient is the client API for Admin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AdminClient interface {
+	// Reload reads the configuration again, like SIGHUP.
+	Reload(ctx context.Context, in *ReloadRequest, opts ...grpc.CallOption) (*ReloadResponse, error)
+}
+
+type adminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminClient(cc grpc.ClientConnInterface) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) Reload(ctx context.Context, in *ReloadRequest, opts ...grpc.CallOption) (*ReloadResponse, error) {
+	out := new(ReloadResponse)
+	err := c.cc.Invoke(ctx, "/msg.Admin/Reload", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServer is the server API for Admin service.
+// All implementations must embed UnimplementedAdminServer
+// for forward compatibility
+type AdminServer interface {
+	// Reload reads the configuration again, like SIGHUP.
+	Reload(context.Context, *ReloadRequest) (*ReloadResponse, error)
+	mustEmbedUnimplementedAdminServer()
+}
+
+// UnimplementedAdminServer must be embedded to have forward compatible implementations.
+type UnimplementedAdminServer struct {
+}
+
+func (UnimplementedAdminServer) Reload(context.Context, *ReloadRequest) (*ReloadResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Reload not implemented")
+}
+func (UnimplementedAdminServer) mustEmbedUnimplementedAdminServer() {}
+
+// UnsafeAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServer will
+// result in compilation errors.
+type UnsafeAdminServer interface {
+	mustEmbedUnimplementedAdminServer()
+}
+
+func RegisterAdminServer(s grpc.ServiceRegistrar, srv AdminServer) {
+	s.RegisterService(&Admin_ServiceDesc, srv)
+}
+
+func _Admin_Reload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReloadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Reload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/msg.Admin/Reload",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Reload(ctx, req.(*ReloadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Admin_ServiceDesc is the grpc.ServiceDesc for Admin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Admin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "msg.Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Reload",
+			Handler:    _Admin_Reload_Handler,
+		},
 	},
+	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/message/proto/message.proto",
 }